@@ -0,0 +1,75 @@
+package httpkit
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"runtime/debug"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/philippe-berto/httpkit/metrics"
+	"github.com/philippe-berto/httpkit/utils"
+)
+
+type AdminConfig struct {
+	Enable bool  `env:"ADMIN_ENABLE" envDefault:"false" yaml:"enable"`
+	Port   int64 `env:"ADMIN_PORT"   envDefault:"9090"  yaml:"port"`
+	// Pprof exposes net/http/pprof under /debug/pprof.
+	Pprof bool `env:"ADMIN_PPROF" envDefault:"false" yaml:"pprof"`
+	// Expvar exposes the expvar variables under /debug/vars.
+	Expvar bool `env:"ADMIN_EXPVAR" envDefault:"false" yaml:"expvar"`
+}
+
+// newAdminServer serves the operational endpoints on their own port, so they
+// are never exposed with the public API.
+func (h *Handler) newAdminServer(cfg AdminConfig) *http.Server {
+	router := chi.NewRouter()
+
+	router.Handle("/metrics", metrics.Handler())
+	router.Get("/buildinfo", getBuildInfo)
+	router.Get("/live", GetStatus)
+	router.Get("/startup", h.getStartup)
+	router.Get("/ready", h.getReady)
+	router.Get("/status", h.getStatus)
+
+	if cfg.Pprof {
+		router.HandleFunc("/debug/pprof/*", pprof.Index)
+		router.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		router.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		router.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		router.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+
+	if cfg.Expvar {
+		router.Handle("/debug/vars", expvar.Handler())
+	}
+
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Port),
+		Handler:           router,
+		ReadHeaderTimeout: defaultReadHeaderTimeout,
+	}
+}
+
+func getBuildInfo(w http.ResponseWriter, r *http.Request) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		_ = utils.Fault(w, http.StatusNotFound, utils.InternalCode, "build info is not available")
+
+		return
+	}
+
+	settings := make(map[string]string, len(info.Settings))
+	for _, setting := range info.Settings {
+		settings[setting.Key] = setting.Value
+	}
+
+	_ = utils.WriteBody(w, http.StatusOK, map[string]interface{}{
+		"go_version": info.GoVersion,
+		"path":       info.Path,
+		"version":    info.Main.Version,
+		"settings":   settings,
+	})
+}
@@ -0,0 +1,55 @@
+package httpkit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmin_Routes(t *testing.T) {
+	t.Run("should serve the operational endpoints", func(t *testing.T) {
+		handler := NewServer(WithAdmin(AdminConfig{Pprof: true, Expvar: true}))
+		admin := handler.adminServer.Handler
+
+		for _, path := range []string{"/metrics", "/buildinfo", "/live", "/ready", "/startup", "/status", "/debug/pprof/", "/debug/vars"} {
+			assert.Equal(t, http.StatusOK, serveRecorded(t, admin, path).Code, path)
+		}
+	})
+
+	t.Run("should keep pprof and expvar off by default", func(t *testing.T) {
+		admin := NewServer(WithAdmin(AdminConfig{})).adminServer.Handler
+
+		assert.Equal(t, http.StatusNotFound, serveRecorded(t, admin, "/debug/pprof/").Code)
+		assert.Equal(t, http.StatusNotFound, serveRecorded(t, admin, "/debug/vars").Code)
+	})
+
+	t.Run("should not expose admin endpoints on the public router", func(t *testing.T) {
+		handler := NewServer(WithAdmin(AdminConfig{Pprof: true}))
+
+		assert.Equal(t, http.StatusNotFound, serveRecorded(t, handler, "/debug/pprof/").Code)
+	})
+}
+
+func TestAdmin_Run(t *testing.T) {
+	t.Run("should return an error when the admin port is taken", func(t *testing.T) {
+		taken, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer taken.Close()
+
+		port := taken.Addr().(*net.TCPAddr).Port
+
+		handler := NewServer(WithPort(0), WithAdmin(AdminConfig{Port: int64(port)}))
+
+		select {
+		case err := <-runForTest(handler, context.Background()):
+			assert.ErrorContains(t, err, "address already in use")
+		case <-time.After(time.Second):
+			t.Fatal("Run did not return after the admin server failed")
+		}
+	})
+}
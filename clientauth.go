@@ -0,0 +1,99 @@
+package httpkit
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/philippe-berto/httpkit/utils"
+)
+
+type (
+	ClientIdentity struct {
+		Subject     string
+		CommonName  string
+		DNSNames    []string
+		URIs        []string
+		SPIFFEID    string
+		Certificate *x509.Certificate
+	}
+
+	clientIdentityKey struct{}
+)
+
+func newClientIdentity(cert *x509.Certificate) *ClientIdentity {
+	identity := &ClientIdentity{
+		Subject:     cert.Subject.String(),
+		CommonName:  cert.Subject.CommonName,
+		DNSNames:    cert.DNSNames,
+		Certificate: cert,
+	}
+
+	for _, uri := range cert.URIs {
+		identity.URIs = append(identity.URIs, uri.String())
+
+		if uri.Scheme == "spiffe" && identity.SPIFFEID == "" {
+			identity.SPIFFEID = uri.String()
+		}
+	}
+
+	return identity
+}
+
+// ClientIdentityFromContext returns the verified client certificate identity
+// stored by ClientIdentityMiddleware.
+func ClientIdentityFromContext(ctx context.Context) (*ClientIdentity, bool) {
+	identity, ok := ctx.Value(clientIdentityKey{}).(*ClientIdentity)
+
+	return identity, ok
+}
+
+// ClientIdentityMiddleware stores the identity of the verified client
+// certificate in the request context and on the current span.
+func ClientIdentityMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		identity := newClientIdentity(r.TLS.VerifiedChains[0][0])
+
+		attributes := []attribute.KeyValue{attribute.String("tls.client.subject", identity.Subject)}
+		if identity.SPIFFEID != "" {
+			attributes = append(attributes, attribute.String("tls.client.spiffe_id", identity.SPIFFEID))
+		}
+
+		trace.SpanFromContext(r.Context()).SetAttributes(attributes...)
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIdentityKey{}, identity)))
+	})
+}
+
+// RequireClientIdentity answers 401 when the request carries no verified client
+// certificate and 403 when authorize rejects its identity. A nil authorize
+// accepts any verified client.
+func RequireClientIdentity(authorize func(*ClientIdentity) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, ok := ClientIdentityFromContext(r.Context())
+			if !ok {
+				_ = utils.Fault(w, http.StatusUnauthorized, utils.InvalidCredentials, "client certificate is required")
+
+				return
+			}
+
+			if authorize != nil && !authorize(identity) {
+				_ = utils.Fault(w, http.StatusForbidden, utils.Forbidden, "client is not allowed")
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
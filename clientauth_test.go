@@ -0,0 +1,157 @@
+package httpkit
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, dir string) (*testCA, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(100),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+
+	return &testCA{cert: cert, key: key}, path
+}
+
+func (ca *testCA) clientCert(t *testing.T, commonName, spiffeID string) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	uri, err := url.Parse(spiffeID)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		URIs:         []*url.URL{uri},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestClientAuth_RequireClientIdentity(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath, serverCert := writeSelfSignedCert(t, dir, 1)
+	ca, caPath := newTestCA(t, dir)
+
+	handler := NewServer(WithTLS(TLSConfig{
+		CertFile:     certPath,
+		KeyFile:      keyPath,
+		ClientCAFile: caPath,
+		ClientAuth:   ClientAuthVerifyIfGiven,
+	}))
+	handler.Router.With(RequireClientIdentity(func(identity *ClientIdentity) bool {
+		return identity.SPIFFEID == "spiffe://example.org/billing"
+	})).Get("/secure", func(w http.ResponseWriter, r *http.Request) {
+		identity, _ := ClientIdentityFromContext(r.Context())
+		_, _ = w.Write([]byte(identity.CommonName + " " + identity.SPIFFEID))
+	})
+
+	addr := serveForTest(t, handler)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCert)
+
+	get := func(certificates ...tls.Certificate) (int, string) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      roots,
+			ServerName:   "localhost",
+			Certificates: certificates,
+		}}}
+
+		resp, err := client.Get("https://" + addr + "/secure")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		return resp.StatusCode, string(body)
+	}
+
+	t.Run("should answer 401 without client certificate", func(t *testing.T) {
+		status, body := get()
+
+		assert.Equal(t, http.StatusUnauthorized, status)
+		assert.Contains(t, body, "invalid_credentials")
+	})
+
+	t.Run("should answer 403 for an unauthorized identity", func(t *testing.T) {
+		status, body := get(ca.clientCert(t, "search", "spiffe://example.org/search"))
+
+		assert.Equal(t, http.StatusForbidden, status)
+		assert.Contains(t, body, "forbidden")
+	})
+
+	t.Run("should expose the identity of an authorized client", func(t *testing.T) {
+		status, body := get(ca.clientCert(t, "billing", "spiffe://example.org/billing"))
+
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "billing spiffe://example.org/billing", body)
+	})
+}
+
+func TestClientAuth_ParseClientAuth(t *testing.T) {
+	t.Run("should default to require when a client CA is set", func(t *testing.T) {
+		clientAuth, err := parseClientAuth("", "ca.crt")
+		require.NoError(t, err)
+
+		assert.Equal(t, tls.RequireAndVerifyClientCert, clientAuth)
+	})
+
+	t.Run("should require a client CA for verification modes", func(t *testing.T) {
+		_, err := parseClientAuth(ClientAuthRequire, "")
+
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	})
+}
@@ -0,0 +1,343 @@
+package httpkit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/caarlos0/env/v11"
+	"go.opentelemetry.io/otel"
+	"gopkg.in/yaml.v3"
+
+	"github.com/philippe-berto/httpkit/metrics"
+	"github.com/philippe-berto/httpkit/tracing"
+	"github.com/philippe-berto/httpkit/utils"
+)
+
+var ErrInvalidConfig = errors.New("invalid httpkit config")
+
+type (
+	Config struct {
+		Port           int                 `env:"HTTP_PORT"           envDefault:"8080" yaml:"port"`
+		UnixSocket     UnixSocketConfig    `yaml:"unix_socket"`
+		SystemdSocket  SystemdSocketConfig `yaml:"systemd_socket"`
+		Restart        RestartConfig       `yaml:"restart"`
+		ProxyProtocol  ProxyProtocolConfig `yaml:"proxy_protocol"`
+		TrustedProxies []string            `env:"HTTP_TRUSTED_PROXIES" envSeparator:"," yaml:"trusted_proxies"`
+		ShutdownGrace  time.Duration       `env:"HTTP_SHUTDOWN_GRACE" envDefault:"10s"  yaml:"shutdown_grace"`
+		DrainDelay     time.Duration       `env:"HTTP_DRAIN_DELAY"    envDefault:"0s"   yaml:"drain_delay"`
+		Timeouts       Timeouts            `yaml:"timeouts"`
+		Limits         Limits              `yaml:"limits"`
+		TLS            TLSConfig           `yaml:"tls"`
+		HTTP2          HTTP2Config         `yaml:"http2"`
+		CORS           CORSConfig          `yaml:"cors"`
+		Tracing        TracingConfig       `yaml:"tracing"`
+		Metrics        metrics.Config      `yaml:"metrics"`
+		Admin          AdminConfig         `yaml:"admin"`
+	}
+
+	Timeouts struct {
+		ReadHeader time.Duration `env:"HTTP_READ_HEADER_TIMEOUT" envDefault:"5s"   yaml:"read_header"`
+		Read       time.Duration `env:"HTTP_READ_TIMEOUT"        envDefault:"30s"  yaml:"read"`
+		Write      time.Duration `env:"HTTP_WRITE_TIMEOUT"       envDefault:"30s"  yaml:"write"`
+		Idle       time.Duration `env:"HTTP_IDLE_TIMEOUT"        envDefault:"120s" yaml:"idle"`
+	}
+
+	CORSConfig struct {
+		Enable       bool   `env:"HTTP_CORS_ENABLE"        envDefault:"false" yaml:"enable"`
+		AllowOrigins string `env:"HTTP_CORS_ALLOW_ORIGINS" yaml:"allow_origins"`
+	}
+
+	TracingConfig struct {
+		Enable bool `env:"TRACING_ENABLE" envDefault:"false" yaml:"enable"`
+		// Propagators lists the formats of the incoming trace context, among
+		// tracecontext, baggage, b3, b3multi and jaeger.
+		Propagators []string `env:"TRACING_PROPAGATORS" envDefault:"tracecontext,baggage" envSeparator:"," yaml:"propagators"`
+		// With an exporter, NewFromConfig builds the TracerProvider of the
+		// middleware with tracing.Setup and shuts it down with the Handler,
+		// see Handler.TracerProvider. Without one, the middleware uses the
+		// global provider.
+		tracing.SetupConfig `yaml:",inline"`
+		// Global also installs the provider built from the exporter as the
+		// global one, so the spans of otel.Tracer are exported with the
+		// requests.
+		Global bool `env:"TRACING_GLOBAL" envDefault:"false" yaml:"global"`
+		// Sampling forces the sampling of some requests, on top of
+		// Sampler. Like the rules of Sampler, it needs Exporter: the global
+		// provider used without one does not honor it.
+		Sampling tracing.SamplingPolicy `yaml:"sampling"`
+	}
+)
+
+// LoadConfig builds a Config from defaults, the optional YAML or JSON file at
+// path and the environment, in increasing order of precedence.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+
+	// Only the envDefault values are applied here, the environment is read last.
+	if err := env.ParseWithOptions(&cfg, env.Options{Environment: map[string]string{}}); err != nil {
+		return cfg, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+
+	if path != "" {
+		if err := loadConfigFile(path, &cfg); err != nil {
+			return cfg, err
+		}
+	}
+
+	// Using an unknown default tag keeps file values for unset variables.
+	if err := env.ParseWithOptions(&cfg, env.Options{DefaultValueTagName: "-"}); err != nil {
+		return cfg, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+
+	return cfg, cfg.Validate()
+}
+
+func loadConfigFile(path string, cfg *Config) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+	default:
+		return fmt.Errorf("%w: unsupported config file extension %q", ErrInvalidConfig, filepath.Ext(path))
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	// JSON is a subset of YAML, so a single decoder handles both formats.
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrInvalidConfig, path, err)
+	}
+
+	return nil
+}
+
+// Validate reports every invalid field of the config at once.
+func (c Config) Validate() error {
+	var errs []error
+
+	if !validPort(int64(c.Port)) {
+		errs = append(errs, fmt.Errorf("%w: port must be between 1 and 65535, got %d", ErrInvalidConfig, c.Port))
+	}
+
+	if c.UnixSocket.Path != "" && c.SystemdSocket.Enable {
+		errs = append(errs, fmt.Errorf("%w: unix_socket and systemd_socket cannot be combined", ErrInvalidConfig))
+	}
+
+	if err := c.UnixSocket.validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if _, err := utils.ParseCIDRs(c.TrustedProxies); err != nil {
+		errs = append(errs, fmt.Errorf("%w: trusted_proxies: %w", ErrInvalidConfig, err))
+	}
+
+	if c.ProxyProtocol.Enable {
+		if err := c.ProxyProtocol.validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if c.Restart.ReadyTimeout < 0 {
+		errs = append(errs, fmt.Errorf("%w: restart.ready_timeout must not be negative, got %s", ErrInvalidConfig, c.Restart.ReadyTimeout))
+	}
+
+	if c.ShutdownGrace < 0 {
+		errs = append(errs, fmt.Errorf("%w: shutdown_grace must not be negative, got %s", ErrInvalidConfig, c.ShutdownGrace))
+	}
+
+	if c.DrainDelay < 0 {
+		errs = append(errs, fmt.Errorf("%w: drain_delay must not be negative, got %s", ErrInvalidConfig, c.DrainDelay))
+	}
+
+	for _, timeout := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"read_header", c.Timeouts.ReadHeader},
+		{"read", c.Timeouts.Read},
+		{"write", c.Timeouts.Write},
+		{"idle", c.Timeouts.Idle},
+	} {
+		if timeout.value < 0 {
+			errs = append(errs, fmt.Errorf("%w: timeouts.%s must not be negative, got %s", ErrInvalidConfig, timeout.name, timeout.value))
+		}
+	}
+
+	if c.Limits.MaxHeaderBytes < 0 {
+		errs = append(errs, fmt.Errorf("%w: limits.max_header_bytes must not be negative, got %d", ErrInvalidConfig, c.Limits.MaxHeaderBytes))
+	}
+
+	if c.Limits.MaxBodyBytes < 0 {
+		errs = append(errs, fmt.Errorf("%w: limits.max_body_bytes must not be negative, got %d", ErrInvalidConfig, c.Limits.MaxBodyBytes))
+	}
+
+	if c.TLS.Enabled() {
+		if err := c.TLS.validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := c.HTTP2.validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if c.HTTP2.H2C && c.TLS.Enabled() {
+		errs = append(errs, fmt.Errorf("%w: http2.h2c cannot be combined with tls", ErrInvalidConfig))
+	}
+
+	if c.CORS.Enable && c.CORS.AllowOrigins == "" {
+		errs = append(errs, fmt.Errorf("%w: cors.allow_origins is required when cors is enabled", ErrInvalidConfig))
+	}
+
+	if c.Tracing.Enable {
+		if _, err := tracing.NewPropagator(c.Tracing.Propagators...); err != nil {
+			errs = append(errs, fmt.Errorf("%w: tracing.propagators: %w", ErrInvalidConfig, err))
+		}
+
+		if err := c.Tracing.SetupConfig.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%w: tracing: %w", ErrInvalidConfig, err))
+		}
+
+		if c.Tracing.Exporter == "" && (len(c.Tracing.Sampler.Rules) > 0 || c.Tracing.Sampling != (tracing.SamplingPolicy{})) {
+			errs = append(errs, fmt.Errorf("%w: tracing.sampler.rules and tracing.sampling need tracing.exporter", ErrInvalidConfig))
+		}
+	}
+
+	if c.Admin.Enable {
+		if !validPort(c.Admin.Port) {
+			errs = append(errs, fmt.Errorf("%w: admin.port must be between 1 and 65535, got %d", ErrInvalidConfig, c.Admin.Port))
+		} else if c.Admin.Port == int64(c.Port) {
+			errs = append(errs, fmt.Errorf("%w: admin.port must differ from port %d", ErrInvalidConfig, c.Port))
+		}
+	}
+
+	if c.Metrics.Enable && !c.Admin.Enable {
+		if !validPort(c.Metrics.Port) {
+			errs = append(errs, fmt.Errorf("%w: metrics.port must be between 1 and 65535, got %d", ErrInvalidConfig, c.Metrics.Port))
+		} else if c.Metrics.Port == int64(c.Port) {
+			errs = append(errs, fmt.Errorf("%w: metrics.port must differ from port %d", ErrInvalidConfig, c.Port))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// NewFromConfig validates cfg and builds the Handler it describes. Extra
+// options are applied after the ones derived from the config, and reported
+// when invalid, see Handler.Err.
+func NewFromConfig(cfg Config, opts ...Option) (*Handler, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	base := cfg.options()
+
+	var provider *tracing.Provider
+
+	if cfg.Tracing.Enable && cfg.Tracing.Exporter != "" {
+		var err error
+
+		provider, err = tracing.Setup(context.Background(), cfg.Tracing.SetupConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		base = append(base, WithTracing(tracing.WithTracerProvider(provider)))
+	}
+
+	h := NewServer(append(base, opts...)...)
+
+	if err := h.Err(); err != nil {
+		if provider != nil {
+			_ = provider.Shutdown(context.Background())
+		}
+
+		return nil, err
+	}
+
+	if provider != nil {
+		h.tracerProvider = provider
+
+		if cfg.Tracing.Global {
+			otel.SetTracerProvider(provider)
+		}
+
+		// The highest priority flushes the spans of the other shutdown hooks.
+		h.OnShutdown(Hook{Name: "tracing", Priority: math.MaxInt, Run: provider.Shutdown})
+	}
+
+	return h, nil
+}
+
+func (c Config) options() []Option {
+	opts := []Option{
+		WithPort(c.Port),
+		WithShutdownTimeout(c.ShutdownGrace),
+		WithDrainDelay(c.DrainDelay),
+		WithTimeouts(c.Timeouts),
+		WithLimits(c.Limits),
+	}
+
+	switch {
+	case c.UnixSocket.Path != "":
+		opts = append(opts, WithUnixSocket(c.UnixSocket))
+	case c.SystemdSocket.Enable:
+		opts = append(opts, WithSystemdSocket(c.SystemdSocket))
+	}
+
+	if c.Restart.Enable {
+		opts = append(opts, WithGracefulRestart(c.Restart))
+	}
+
+	if c.ProxyProtocol.Enable {
+		opts = append(opts, WithProxyProtocol(c.ProxyProtocol))
+	}
+
+	if len(c.TrustedProxies) > 0 {
+		opts = append(opts, WithTrustedProxies(c.TrustedProxies...))
+	}
+
+	if c.TLS.Enabled() {
+		opts = append(opts, WithTLS(c.TLS))
+	}
+
+	if c.HTTP2 != (HTTP2Config{}) {
+		opts = append(opts, WithHTTP2(c.HTTP2))
+	}
+
+	if c.Tracing.Enable {
+		opts = append(opts, WithTracing(tracing.WithSampling(c.Tracing.Sampling)), WithPropagators(c.Tracing.Propagators...))
+	}
+
+	if c.Metrics.Enable {
+		opts = append(opts, WithMetrics())
+	}
+
+	switch {
+	case c.Admin.Enable:
+		opts = append(opts, WithAdmin(c.Admin))
+	case c.Metrics.Enable:
+		opts = append(opts, WithMetricsServer(c.Metrics.Port))
+	}
+
+	if c.CORS.Enable {
+		opts = append(opts, WithCORS(c.CORS.AllowOrigins))
+	}
+
+	return opts
+}
+
+func validPort(port int64) bool {
+	return port > 0 && port <= 65535
+}
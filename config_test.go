@@ -0,0 +1,224 @@
+package httpkit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+
+	"github.com/philippe-berto/httpkit/tracing"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestConfig_LoadConfig(t *testing.T) {
+	t.Run("should apply defaults without file", func(t *testing.T) {
+		cfg, err := LoadConfig("")
+		require.NoError(t, err)
+
+		assert.Equal(t, 8080, cfg.Port)
+		assert.Equal(t, 10*time.Second, cfg.ShutdownGrace)
+		assert.False(t, cfg.Metrics.Enable)
+	})
+
+	t.Run("should load yaml file and let env override it", func(t *testing.T) {
+		path := writeConfigFile(t, "httpkit.yaml", `
+port: 9000
+shutdown_grace: 30s
+timeouts:
+  read_header: 2s
+cors:
+  enable: true
+  allow_origins: https://file.example.com
+`)
+		t.Setenv("HTTP_CORS_ALLOW_ORIGINS", "https://env.example.com")
+
+		cfg, err := LoadConfig(path)
+		require.NoError(t, err)
+
+		assert.Equal(t, 9000, cfg.Port)
+		assert.Equal(t, 30*time.Second, cfg.ShutdownGrace)
+		assert.Equal(t, 2*time.Second, cfg.Timeouts.ReadHeader)
+		assert.True(t, cfg.CORS.Enable)
+		assert.Equal(t, "https://env.example.com", cfg.CORS.AllowOrigins)
+	})
+
+	t.Run("should load json file", func(t *testing.T) {
+		path := writeConfigFile(t, "httpkit.json", `{"port": 9001, "metrics": {"enable": true, "port": 9100}}`)
+
+		cfg, err := LoadConfig(path)
+		require.NoError(t, err)
+
+		assert.Equal(t, 9001, cfg.Port)
+		assert.True(t, cfg.Metrics.Enable)
+		assert.Equal(t, int64(9100), cfg.Metrics.Port)
+	})
+
+	t.Run("should reject unknown fields and extensions", func(t *testing.T) {
+		_, err := LoadConfig(writeConfigFile(t, "httpkit.yaml", "prot: 9000\n"))
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+
+		_, err = LoadConfig(writeConfigFile(t, "httpkit.toml", "port = 9000\n"))
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	})
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Run("should report every invalid field", func(t *testing.T) {
+		cfg := Config{
+			Port:     0,
+			Timeouts: Timeouts{Read: -time.Second},
+			CORS:     CORSConfig{Enable: true},
+		}
+
+		err := cfg.Validate()
+		require.ErrorIs(t, err, ErrInvalidConfig)
+		assert.Contains(t, err.Error(), "port must be between 1 and 65535, got 0")
+		assert.Contains(t, err.Error(), "timeouts.read must not be negative")
+		assert.Contains(t, err.Error(), "cors.allow_origins is required")
+	})
+
+	t.Run("should reject metrics on the server port", func(t *testing.T) {
+		cfg := Config{Port: 8080}
+		cfg.Metrics.Enable = true
+		cfg.Metrics.Port = 8080
+
+		_, err := NewFromConfig(cfg)
+		assert.ErrorContains(t, err, "metrics.port must differ from port 8080")
+	})
+
+	t.Run("should build handler from valid config", func(t *testing.T) {
+		handler, err := NewFromConfig(Config{Port: 9002, ShutdownGrace: time.Second, Timeouts: Timeouts{Idle: time.Minute}})
+		require.NoError(t, err)
+
+		assert.Equal(t, ":9002", handler.server.Addr)
+		assert.Equal(t, time.Minute, handler.server.IdleTimeout)
+		assert.Equal(t, time.Second, handler.shutdownTimeout)
+	})
+
+	t.Run("should report invalid tracing setup", func(t *testing.T) {
+		cfg := Config{Port: 8080}
+		cfg.Tracing.Enable = true
+		cfg.Tracing.Exporter = "zipkin"
+
+		err := cfg.Validate()
+		require.ErrorIs(t, err, ErrInvalidConfig)
+		assert.ErrorIs(t, err, tracing.ErrInvalidSetup)
+	})
+
+	t.Run("should reject sampling settings without exporter", func(t *testing.T) {
+		cfg := Config{Port: 8080}
+		cfg.Tracing.Enable = true
+		cfg.Tracing.Sampling.DebugHeader = "X-Debug-Trace"
+		assert.ErrorContains(t, cfg.Validate(), "tracing.sampler.rules and tracing.sampling need tracing.exporter")
+
+		cfg.Tracing.Sampling = tracing.SamplingPolicy{}
+		cfg.Tracing.Sampler.Rules = []tracing.SamplingRule{{Route: "/poll", Sample: tracing.SampleNever}}
+		assert.ErrorIs(t, cfg.Validate(), ErrInvalidConfig)
+
+		cfg.Tracing.Exporter = tracing.ExporterMemory
+		cfg.Tracing.Sampling.SampleErrors = true
+		handler, err := NewFromConfig(cfg)
+		require.NoError(t, err)
+		require.NoError(t, handler.runShutdownHooks(context.Background()))
+	})
+}
+
+func TestConfig_Tracing(t *testing.T) {
+	t.Run("should load the exporter from the tracing section", func(t *testing.T) {
+		cfg, err := LoadConfig(writeConfigFile(t, "httpkit.yaml", `
+tracing:
+  enable: true
+  service_name: orders
+  exporter: otlp-http
+  endpoint: collector:4318
+  sampler:
+    type: parentbased_traceidratio
+    ratio: 0.25
+    rules:
+      - route: /poll
+        ratio: 0
+      - route: /health/deep
+        sample: never
+  sampling:
+    debug_header: X-Debug-Trace
+    sample_errors: true
+`))
+		require.NoError(t, err)
+
+		assert.Equal(t, "orders", cfg.Tracing.ServiceName)
+		assert.Equal(t, tracing.ExporterOTLPHTTP, cfg.Tracing.Exporter)
+		assert.Equal(t, "collector:4318", cfg.Tracing.Endpoint)
+		assert.Equal(t, 0.25, cfg.Tracing.Sampler.Ratio)
+		assert.Equal(t, []tracing.SamplingRule{
+			{Route: "/poll"},
+			{Route: "/health/deep", Sample: tracing.SampleNever},
+		}, cfg.Tracing.Sampler.Rules)
+		assert.Equal(t, tracing.SamplingPolicy{
+			DebugHeader:  "X-Debug-Trace",
+			SampleErrors: true,
+		}, cfg.Tracing.Sampling)
+	})
+
+	t.Run("should export the spans of the handler and flush them on shutdown", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "traces.json")
+
+		cfg := Config{Port: 8080}
+		cfg.Tracing.Enable = true
+		cfg.Tracing.Exporter = tracing.ExporterFile
+		cfg.Tracing.FilePath = path
+
+		handler, err := NewFromConfig(cfg)
+		require.NoError(t, err)
+
+		handler.Router.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {})
+		handler.Router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/42", nil))
+
+		require.NotNil(t, handler.TracerProvider())
+		_, span := handler.TracerProvider().Tracer("test").Start(context.Background(), "child")
+		span.End()
+
+		require.NoError(t, handler.runShutdownHooks(context.Background()))
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Contains(t, string(content), `"Name":"GET /items/{id}"`)
+		assert.Contains(t, string(content), `"Name":"child"`)
+	})
+
+	t.Run("should install the provider globally when asked", func(t *testing.T) {
+		previous := otel.GetTracerProvider()
+		t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+		cfg := Config{Port: 8080}
+		cfg.Tracing.Enable = true
+		cfg.Tracing.Exporter = tracing.ExporterMemory
+		cfg.Tracing.Global = true
+
+		handler, err := NewFromConfig(cfg)
+		require.NoError(t, err)
+
+		assert.Same(t, handler.TracerProvider(), otel.GetTracerProvider())
+	})
+
+	t.Run("should not expose a provider without exporter", func(t *testing.T) {
+		handler, err := NewFromConfig(Config{Port: 8080, Tracing: TracingConfig{Enable: true}})
+		require.NoError(t, err)
+
+		assert.Nil(t, handler.TracerProvider())
+	})
+}
@@ -0,0 +1,119 @@
+package httpkit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/philippe-berto/httpkit/utils"
+)
+
+const (
+	defaultReadHeaderTimeout = 5 * time.Second
+	defaultReadTimeout       = 30 * time.Second
+	defaultWriteTimeout      = 30 * time.Second
+	defaultIdleTimeout       = 120 * time.Second
+	defaultMaxHeaderBytes    = 64 << 10
+	defaultMaxBodyBytes      = 10 << 20
+)
+
+type (
+	Limits struct {
+		MaxHeaderBytes int `env:"HTTP_MAX_HEADER_BYTES" envDefault:"65536" yaml:"max_header_bytes"`
+		// MaxBodyBytes caps every request body. Zero disables the global cap;
+		// BodyLimit still applies per route.
+		MaxBodyBytes int64 `env:"HTTP_MAX_BODY_BYTES" envDefault:"10485760" yaml:"max_body_bytes"`
+	}
+
+	limitedBody struct {
+		io.ReadCloser
+		remaining int64
+		limit     int64
+		err       error
+	}
+
+	bodyLimitKey struct{}
+)
+
+// DefaultTimeouts protects the server against slow clients while leaving room
+// for ordinary API calls.
+func DefaultTimeouts() Timeouts {
+	return Timeouts{
+		ReadHeader: defaultReadHeaderTimeout,
+		Read:       defaultReadTimeout,
+		Write:      defaultWriteTimeout,
+		Idle:       defaultIdleTimeout,
+	}
+}
+
+func DefaultLimits() Limits {
+	return Limits{
+		MaxHeaderBytes: defaultMaxHeaderBytes,
+		MaxBodyBytes:   defaultMaxBodyBytes,
+	}
+}
+
+// BodyLimit caps the request body at maxBytes, replacing any limit set
+// earlier in the chain, so a route can lower or raise the global cap.
+// Requests announcing a larger Content-Length are rejected with 413 before
+// the handler runs; utils.ReadBody reports ErrBodyTooLarge for the others.
+func BodyLimit(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if maxBytes <= 0 || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			if r.ContentLength > maxBytes {
+				_ = utils.BodyFault(w, utils.ErrBodyTooLarge)
+
+				return
+			}
+
+			body, ok := r.Context().Value(bodyLimitKey{}).(io.ReadCloser)
+			if !ok {
+				body = r.Body
+			}
+
+			ctx := context.WithValue(r.Context(), bodyLimitKey{}, body)
+			r = r.WithContext(ctx)
+			r.Body = &limitedBody{ReadCloser: body, remaining: maxBytes, limit: maxBytes}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Read mirrors http.MaxBytesReader, reading one extra byte to tell a body of
+// exactly limit bytes from a larger one.
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if int64(len(p))-1 > b.remaining {
+		p = p[:b.remaining+1]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+
+	if int64(n) <= b.remaining {
+		b.remaining -= int64(n)
+		b.err = err
+
+		return n, err
+	}
+
+	n = int(b.remaining)
+	b.remaining = 0
+	b.err = &http.MaxBytesError{Limit: b.limit}
+
+	return n, b.err
+}
@@ -0,0 +1,102 @@
+package httpkit
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/philippe-berto/httpkit/utils"
+)
+
+func readBodyHandler(w http.ResponseWriter, r *http.Request) {
+	var body map[string]interface{}
+	if err := utils.ReadBody(r, &body); err != nil {
+		_ = utils.BodyFault(w, err)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// chunkedBody hides the length of the payload from the server.
+type chunkedBody struct {
+	io.Reader
+}
+
+func TestHardening_BodyLimit(t *testing.T) {
+	handler := NewServer(WithLimits(Limits{MaxBodyBytes: 16}))
+	handler.Router.Post("/small", readBodyHandler)
+	handler.Router.With(BodyLimit(1024)).Post("/large", readBodyHandler)
+
+	payload := `{"name":"a payload over sixteen bytes"}`
+
+	t.Run("should reject an announced oversized body before the handler", func(t *testing.T) {
+		responseWriter := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodPost, "/small", strings.NewReader(payload))
+		require.NoError(t, err)
+
+		handler.ServeHTTP(responseWriter, request)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, responseWriter.Code)
+		assert.Contains(t, responseWriter.Body.String(), utils.BodyTooLarge)
+	})
+
+	t.Run("should stop reading a streamed oversized body", func(t *testing.T) {
+		responseWriter := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodPost, "/small", chunkedBody{strings.NewReader(payload)})
+		require.NoError(t, err)
+
+		handler.ServeHTTP(responseWriter, request)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, responseWriter.Code)
+	})
+
+	t.Run("should let a route raise the global limit", func(t *testing.T) {
+		responseWriter := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodPost, "/large", chunkedBody{strings.NewReader(payload)})
+		require.NoError(t, err)
+
+		handler.ServeHTTP(responseWriter, request)
+
+		assert.Equal(t, http.StatusNoContent, responseWriter.Code)
+	})
+}
+
+func TestHardening_Timeouts(t *testing.T) {
+	t.Run("should apply safe defaults", func(t *testing.T) {
+		handler := NewServer()
+
+		assert.Equal(t, defaultReadHeaderTimeout, handler.server.ReadHeaderTimeout)
+		assert.Equal(t, defaultReadTimeout, handler.server.ReadTimeout)
+		assert.Equal(t, defaultWriteTimeout, handler.server.WriteTimeout)
+		assert.Equal(t, defaultIdleTimeout, handler.server.IdleTimeout)
+		assert.Equal(t, defaultMaxHeaderBytes, handler.server.MaxHeaderBytes)
+	})
+
+	t.Run("should answer 408 when the body is not received in time", func(t *testing.T) {
+		handler := NewServer(WithTimeouts(Timeouts{Read: 100 * time.Millisecond}))
+		handler.Router.Post("/slow", readBodyHandler)
+
+		conn, err := net.Dial("tcp", serveForTest(t, handler))
+		require.NoError(t, err)
+		defer conn.Close()
+
+		_, err = conn.Write([]byte("POST /slow HTTP/1.1\r\nHost: localhost\r\nContent-Length: 64\r\n\r\n{"))
+		require.NoError(t, err)
+
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusRequestTimeout, resp.StatusCode)
+	})
+}
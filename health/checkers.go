@@ -0,0 +1,163 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime"
+)
+
+type (
+	// DetailedChecker is a Checker that also reports details, shown in the
+	// /status report whether the check passes or not.
+	DetailedChecker interface {
+		Checker
+		CheckDetails(ctx context.Context) (map[string]interface{}, error)
+	}
+
+	// Pinger is implemented by *sql.DB and *sql.Conn.
+	Pinger interface {
+		PingContext(ctx context.Context) error
+	}
+
+	detailedFunc struct {
+		name string
+		fn   func(ctx context.Context) (map[string]interface{}, error)
+	}
+)
+
+func (c detailedFunc) Name() string {
+	return c.name
+}
+
+func (c detailedFunc) Check(ctx context.Context) error {
+	_, err := c.fn(ctx)
+
+	return err
+}
+
+func (c detailedFunc) CheckDetails(ctx context.Context) (map[string]interface{}, error) {
+	return c.fn(ctx)
+}
+
+// SQL pings the database. The pool statistics are reported when db is a *sql.DB.
+func SQL(name string, db Pinger) Checker {
+	return detailedFunc{name: name, fn: func(ctx context.Context) (map[string]interface{}, error) {
+		details := map[string]interface{}{}
+
+		if stats, ok := db.(interface{ Stats() sql.DBStats }); ok {
+			s := stats.Stats()
+			details["open_connections"] = s.OpenConnections
+			details["in_use"] = s.InUse
+			details["idle"] = s.Idle
+		}
+
+		return details, db.PingContext(ctx)
+	}}
+}
+
+// TCP dials address and closes the connection right away.
+func TCP(name, address string) Checker {
+	return detailedFunc{name: name, fn: func(ctx context.Context) (map[string]interface{}, error) {
+		details := map[string]interface{}{"address": address}
+
+		var dialer net.Dialer
+
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return details, err
+		}
+
+		return details, conn.Close()
+	}}
+}
+
+// HTTPGet requests url and expects expectedStatus back. A nil client uses
+// http.DefaultClient.
+func HTTPGet(name, url string, expectedStatus int, client *http.Client) Checker {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return detailedFunc{name: name, fn: func(ctx context.Context) (map[string]interface{}, error) {
+		details := map[string]interface{}{"url": url}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return details, err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return details, err
+		}
+		defer resp.Body.Close()
+
+		details["status_code"] = resp.StatusCode
+
+		if resp.StatusCode != expectedStatus {
+			return details, fmt.Errorf("unexpected status %d, expected %d", resp.StatusCode, expectedStatus)
+		}
+
+		return details, nil
+	}}
+}
+
+// DiskFree fails when the filesystem holding path has less than minFreeBytes
+// available to unprivileged users.
+func DiskFree(name, path string, minFreeBytes uint64) Checker {
+	return detailedFunc{name: name, fn: func(context.Context) (map[string]interface{}, error) {
+		details := map[string]interface{}{"path": path, "min_free_bytes": minFreeBytes}
+
+		free, total, err := diskUsage(path)
+		if err != nil {
+			return details, err
+		}
+
+		details["free_bytes"] = free
+		details["total_bytes"] = total
+
+		if free < minFreeBytes {
+			return details, fmt.Errorf("%d bytes free, below %d", free, minFreeBytes)
+		}
+
+		return details, nil
+	}}
+}
+
+// Goroutines fails when more than max goroutines are running, which usually
+// reveals a leak.
+func Goroutines(name string, max int) Checker {
+	return detailedFunc{name: name, fn: func(context.Context) (map[string]interface{}, error) {
+		count := runtime.NumGoroutine()
+		details := map[string]interface{}{"count": count, "max": max}
+
+		if count > max {
+			return details, fmt.Errorf("%d goroutines running, above %d", count, max)
+		}
+
+		return details, nil
+	}}
+}
+
+// Memory fails when the heap in use exceeds maxHeapBytes.
+func Memory(name string, maxHeapBytes uint64) Checker {
+	return detailedFunc{name: name, fn: func(context.Context) (map[string]interface{}, error) {
+		var stats runtime.MemStats
+		runtime.ReadMemStats(&stats)
+
+		details := map[string]interface{}{
+			"heap_alloc_bytes": stats.HeapAlloc,
+			"sys_bytes":        stats.Sys,
+			"max_heap_bytes":   maxHeapBytes,
+		}
+
+		if stats.HeapAlloc > maxHeapBytes {
+			return details, fmt.Errorf("%d heap bytes in use, above %d", stats.HeapAlloc, maxHeapBytes)
+		}
+
+		return details, nil
+	}}
+}
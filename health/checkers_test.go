@@ -0,0 +1,160 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDriver stands in for a real database: pinging succeeds unless the DSN
+// is "down".
+type (
+	fakeDriver struct{}
+
+	fakeConn struct {
+		dsn string
+	}
+)
+
+func init() {
+	sql.Register("healthfake", fakeDriver{})
+}
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	return &fakeConn{dsn: dsn}, nil
+}
+
+func (c *fakeConn) Ping(context.Context) error {
+	if c.dsn == "down" {
+		return errors.New("database is down")
+	}
+
+	return nil
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+func runOne(checker Checker) Result {
+	registry := NewRegistry()
+	registry.Register(checker)
+
+	return registry.Run(context.Background()).Checks[0]
+}
+
+func TestCheckers_SQL(t *testing.T) {
+	t.Run("should ping the database and report pool stats", func(t *testing.T) {
+		db, err := sql.Open("healthfake", "up")
+		require.NoError(t, err)
+		defer db.Close()
+
+		result := runOne(SQL("db", db))
+
+		assert.Equal(t, StatusUp, result.Status)
+		assert.Contains(t, result.Details, "open_connections")
+	})
+
+	t.Run("should fail when the ping fails", func(t *testing.T) {
+		db, err := sql.Open("healthfake", "down")
+		require.NoError(t, err)
+		defer db.Close()
+
+		result := runOne(SQL("db", db))
+
+		assert.Equal(t, StatusDown, result.Status)
+		assert.Equal(t, "database is down", result.Error)
+	})
+}
+
+func TestCheckers_TCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	address := ln.Addr().String()
+
+	t.Run("should dial a listening address", func(t *testing.T) {
+		assert.Equal(t, StatusUp, runOne(TCP("broker", address)).Status)
+	})
+
+	t.Run("should fail on a closed address", func(t *testing.T) {
+		require.NoError(t, ln.Close())
+
+		result := runOne(TCP("broker", address))
+
+		assert.Equal(t, StatusDown, result.Status)
+		assert.Equal(t, address, result.Details["address"])
+	})
+}
+
+func TestCheckers_HTTPGet(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusBadGateway)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	t.Run("should pass on the expected status", func(t *testing.T) {
+		result := runOne(HTTPGet("upstream", upstream.URL+"/status", http.StatusOK, nil))
+
+		assert.Equal(t, StatusUp, result.Status)
+		assert.Equal(t, http.StatusOK, result.Details["status_code"])
+	})
+
+	t.Run("should fail on another status", func(t *testing.T) {
+		result := runOne(HTTPGet("upstream", upstream.URL+"/broken", http.StatusOK, upstream.Client()))
+
+		assert.Equal(t, StatusDown, result.Status)
+		assert.Equal(t, "unexpected status 502, expected 200", result.Error)
+	})
+}
+
+func TestCheckers_DiskFree(t *testing.T) {
+	t.Run("should pass above the threshold", func(t *testing.T) {
+		result := runOne(DiskFree("disk", t.TempDir(), 1))
+
+		assert.Equal(t, StatusUp, result.Status)
+		assert.NotZero(t, result.Details["total_bytes"])
+	})
+
+	t.Run("should fail below the threshold", func(t *testing.T) {
+		assert.Equal(t, StatusDown, runOne(DiskFree("disk", t.TempDir(), math.MaxUint64)).Status)
+	})
+}
+
+func TestCheckers_Runtime(t *testing.T) {
+	t.Run("should check the goroutine ceiling", func(t *testing.T) {
+		assert.Equal(t, StatusUp, runOne(Goroutines("goroutines", 10000)).Status)
+		assert.Equal(t, StatusDown, runOne(Goroutines("goroutines", 1)).Status)
+	})
+
+	t.Run("should check the heap ceiling", func(t *testing.T) {
+		assert.Equal(t, StatusUp, runOne(Memory("memory", math.MaxUint64)).Status)
+
+		result := runOne(Memory("memory", 1))
+		assert.Equal(t, StatusDown, result.Status)
+		assert.Contains(t, result.Details, "heap_alloc_bytes")
+	})
+}
@@ -0,0 +1,16 @@
+//go:build linux || darwin
+
+package health
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+func diskUsage(path string) (free, total uint64, err error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return 0, 0, err
+	}
+
+	return uint64(stat.Bavail) * uint64(stat.Bsize), uint64(stat.Blocks) * uint64(stat.Bsize), nil
+}
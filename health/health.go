@@ -0,0 +1,306 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/philippe-berto/httpkit/utils"
+)
+
+const (
+	StatusUp       Status = "up"
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+
+	DefaultTimeout = 5 * time.Second
+)
+
+var ErrTimeout = errors.New("health check timed out")
+
+type (
+	Status string
+
+	Checker interface {
+		Name() string
+		Check(ctx context.Context) error
+	}
+
+	Result struct {
+		Name     string                 `json:"name"`
+		Status   Status                 `json:"status"`
+		Critical bool                   `json:"critical"`
+		Latency  time.Duration          `json:"latency"`
+		Error    string                 `json:"error,omitempty"`
+		Details  map[string]interface{} `json:"details,omitempty"`
+	}
+
+	Report struct {
+		Status Status   `json:"status"`
+		Checks []Result `json:"checks"`
+	}
+
+	CheckOption func(*check)
+
+	// Registry runs the registered checks in parallel and aggregates them in
+	// a Report.
+	Registry struct {
+		mu     sync.RWMutex
+		checks []*check
+	}
+
+	check struct {
+		checker  Checker
+		critical bool
+		timeout  time.Duration
+		cacheTTL time.Duration
+
+		mu        sync.Mutex
+		cached    Result
+		expiresAt time.Time
+		running   *flight
+	}
+
+	// flight is a run of a check shared by the requests arriving meanwhile.
+	flight struct {
+		done   chan struct{}
+		result Result
+	}
+
+	checkerFunc struct {
+		name string
+		fn   func(ctx context.Context) error
+	}
+
+	namedChecker struct {
+		Checker
+		name string
+	}
+
+	namedDetailedChecker struct {
+		DetailedChecker
+		name string
+	}
+)
+
+// CheckerFunc adapts fn to a Checker reported under name.
+func CheckerFunc(name string, fn func(ctx context.Context) error) Checker {
+	return checkerFunc{name: name, fn: fn}
+}
+
+func (c checkerFunc) Name() string {
+	return c.name
+}
+
+func (c checkerFunc) Check(ctx context.Context) error {
+	return c.fn(ctx)
+}
+
+// Named reports checker under name, keeping its details if it is a
+// DetailedChecker.
+func Named(name string, checker Checker) Checker {
+	if detailed, ok := checker.(DetailedChecker); ok {
+		return namedDetailedChecker{DetailedChecker: detailed, name: name}
+	}
+
+	return namedChecker{Checker: checker, name: name}
+}
+
+func (c namedChecker) Name() string {
+	return c.name
+}
+
+func (c namedDetailedChecker) Name() string {
+	return c.name
+}
+
+// NonCritical reports a failure of the check as degraded instead of down, so
+// it does not fail readiness.
+func NonCritical() CheckOption {
+	return func(c *check) {
+		c.critical = false
+	}
+}
+
+// WithTimeout bounds each run of the check. Defaults to DefaultTimeout.
+func WithTimeout(timeout time.Duration) CheckOption {
+	return func(c *check) {
+		c.timeout = timeout
+	}
+}
+
+// WithCacheTTL reuses the last result for ttl instead of running the check
+// on every request. Disabled by default.
+func WithCacheTTL(ttl time.Duration) CheckOption {
+	return func(c *check) {
+		c.cacheTTL = ttl
+	}
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a checker, critical unless NonCritical is given.
+func (r *Registry) Register(checker Checker, opts ...CheckOption) {
+	c := &check{checker: checker, critical: true, timeout: DefaultTimeout}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	r.mu.Lock()
+	r.checks = append(r.checks, c)
+	r.mu.Unlock()
+}
+
+// Run executes every check in parallel and returns their results in
+// registration order.
+func (r *Registry) Run(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := append([]*check(nil), r.checks...)
+	r.mu.RUnlock()
+
+	report := Report{Status: StatusUp, Checks: make([]Result, len(checks))}
+
+	var wg sync.WaitGroup
+
+	for i, c := range checks {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = c.run(ctx)
+		}()
+	}
+
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status == StatusUp {
+			continue
+		}
+
+		if result.Critical {
+			report.Status = StatusDown
+
+			break
+		}
+
+		report.Status = StatusDegraded
+	}
+
+	return report
+}
+
+// Handler answers the report as JSON, with 503 when a critical check fails.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		report := r.Run(req.Context())
+
+		_ = utils.WriteBody(w, report.HTTPStatus(), report)
+	})
+}
+
+func (r Report) HTTPStatus() int {
+	if r.Status == StatusDown {
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusOK
+}
+
+func (r Result) MarshalJSON() ([]byte, error) {
+	type result Result
+
+	return json.Marshal(struct {
+		result
+		Latency string `json:"latency"`
+	}{result(r), r.Latency.String()})
+}
+
+// run returns the cached result, or joins the run in progress so concurrent
+// probes wait for one check instead of queueing behind each other. The shared
+// run is bounded by the check timeout rather than the context of the request
+// starting it.
+func (c *check) run(ctx context.Context) Result {
+	c.mu.Lock()
+
+	if c.cacheTTL > 0 && time.Now().Before(c.expiresAt) {
+		defer c.mu.Unlock()
+
+		return c.cached
+	}
+
+	if f := c.running; f != nil {
+		c.mu.Unlock()
+		<-f.done
+
+		return f.result
+	}
+
+	f := &flight{done: make(chan struct{})}
+	c.running = f
+	c.mu.Unlock()
+
+	f.result = c.execute(context.WithoutCancel(ctx))
+
+	c.mu.Lock()
+	c.cached = f.result
+	c.expiresAt = time.Now().Add(c.cacheTTL)
+	c.running = nil
+	c.mu.Unlock()
+
+	close(f.done)
+
+	return f.result
+}
+
+func (c *check) execute(ctx context.Context) Result {
+	result := Result{Name: c.checker.Name(), Status: StatusUp, Critical: c.critical}
+
+	start := time.Now()
+	details, err := c.check(ctx)
+	result.Latency = time.Since(start)
+	result.Details = details
+
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// check returns when the timeout expires even if the checker ignores ctx.
+func (c *check) check(ctx context.Context) (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	type outcome struct {
+		details map[string]interface{}
+		err     error
+	}
+
+	done := make(chan outcome, 1)
+
+	go func() {
+		if detailed, ok := c.checker.(DetailedChecker); ok {
+			details, err := detailed.CheckDetails(ctx)
+			done <- outcome{details, err}
+
+			return
+		}
+
+		done <- outcome{err: c.checker.Check(ctx)}
+	}()
+
+	select {
+	case out := <-done:
+		return out.details, out.err
+	case <-ctx.Done():
+		return nil, ErrTimeout
+	}
+}
@@ -0,0 +1,171 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func passing(name string) Checker {
+	return CheckerFunc(name, func(context.Context) error { return nil })
+}
+
+func failing(name string) Checker {
+	return CheckerFunc(name, func(context.Context) error { return errors.New(name + " unreachable") })
+}
+
+func TestHealth_Run(t *testing.T) {
+	t.Run("should be up when every check passes", func(t *testing.T) {
+		registry := NewRegistry()
+		registry.Register(passing("db"))
+		registry.Register(passing("cache"), NonCritical())
+
+		report := registry.Run(context.Background())
+
+		assert.Equal(t, StatusUp, report.Status)
+		assert.Equal(t, http.StatusOK, report.HTTPStatus())
+		require.Len(t, report.Checks, 2)
+		assert.Equal(t, "db", report.Checks[0].Name)
+		assert.True(t, report.Checks[0].Critical)
+		assert.False(t, report.Checks[1].Critical)
+	})
+
+	t.Run("should be degraded when a non critical check fails", func(t *testing.T) {
+		registry := NewRegistry()
+		registry.Register(passing("db"))
+		registry.Register(failing("cache"), NonCritical())
+
+		report := registry.Run(context.Background())
+
+		assert.Equal(t, StatusDegraded, report.Status)
+		assert.Equal(t, http.StatusOK, report.HTTPStatus())
+		assert.Equal(t, "cache unreachable", report.Checks[1].Error)
+	})
+
+	t.Run("should be down when a critical check fails", func(t *testing.T) {
+		registry := NewRegistry()
+		registry.Register(failing("cache"), NonCritical())
+		registry.Register(failing("db"))
+
+		report := registry.Run(context.Background())
+
+		assert.Equal(t, StatusDown, report.Status)
+		assert.Equal(t, http.StatusServiceUnavailable, report.HTTPStatus())
+	})
+
+	t.Run("should run checks in parallel and time them out", func(t *testing.T) {
+		slow := func(name string) Checker {
+			return CheckerFunc(name, func(ctx context.Context) error {
+				select {}
+			})
+		}
+
+		registry := NewRegistry()
+		registry.Register(slow("a"), WithTimeout(50*time.Millisecond))
+		registry.Register(slow("b"), WithTimeout(50*time.Millisecond))
+
+		start := time.Now()
+		report := registry.Run(context.Background())
+
+		assert.Less(t, time.Since(start), 90*time.Millisecond)
+		assert.Equal(t, ErrTimeout.Error(), report.Checks[0].Error)
+		assert.Equal(t, ErrTimeout.Error(), report.Checks[1].Error)
+	})
+
+	t.Run("should cache results for the configured ttl", func(t *testing.T) {
+		var calls atomic.Int32
+
+		registry := NewRegistry()
+		registry.Register(CheckerFunc("db", func(context.Context) error {
+			calls.Add(1)
+
+			return nil
+		}), WithCacheTTL(time.Minute))
+
+		registry.Run(context.Background())
+		registry.Run(context.Background())
+
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("should share a running check between concurrent reports", func(t *testing.T) {
+		var calls atomic.Int32
+		release := make(chan struct{})
+
+		registry := NewRegistry()
+		registry.Register(CheckerFunc("db", func(context.Context) error {
+			calls.Add(1)
+			<-release
+
+			return nil
+		}))
+
+		reports := make(chan Report, 5)
+		for range 5 {
+			go func() { reports <- registry.Run(context.Background()) }()
+		}
+
+		require.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+
+		for range 5 {
+			assert.Equal(t, StatusUp, (<-reports).Status)
+		}
+
+		assert.Equal(t, int32(1), calls.Load())
+	})
+}
+
+func TestHealth_Handler(t *testing.T) {
+	t.Run("should answer the report as json", func(t *testing.T) {
+		registry := NewRegistry()
+		registry.Register(failing("db"))
+
+		responseWriter := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, "/status", nil)
+		require.NoError(t, err)
+
+		registry.Handler().ServeHTTP(responseWriter, request)
+
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(responseWriter.Body.Bytes(), &body))
+
+		assert.Equal(t, http.StatusServiceUnavailable, responseWriter.Code)
+		assert.Equal(t, "down", body["status"])
+
+		check := body["checks"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "db", check["name"])
+		assert.Equal(t, "db unreachable", check["error"])
+		assert.NotEmpty(t, check["latency"])
+	})
+}
+
+func TestHealth_Named(t *testing.T) {
+	t.Run("should rename a checker", func(t *testing.T) {
+		registry := NewRegistry()
+		registry.Register(Named("billing/db", passing("db")))
+
+		report := registry.Run(context.Background())
+
+		assert.Equal(t, "billing/db", report.Checks[0].Name)
+	})
+
+	t.Run("should keep the details of a detailed checker", func(t *testing.T) {
+		registry := NewRegistry()
+		registry.Register(Named("billing/goroutines", Goroutines("goroutines", 1_000_000)))
+
+		report := registry.Run(context.Background())
+
+		assert.Equal(t, "billing/goroutines", report.Checks[0].Name)
+		assert.NotEmpty(t, report.Checks[0].Details)
+	})
+}
@@ -0,0 +1,93 @@
+package httpkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Hook is a named callback run by Run on start or shutdown. Hooks run one at
+// a time by ascending Priority, then in registration order for start hooks
+// and in reverse registration order for shutdown hooks, so what started first
+// stops last.
+type Hook struct {
+	Name     string
+	Priority int
+	// Timeout bounds the hook. Zero leaves it bounded only by the overall
+	// start context or shutdown grace period.
+	Timeout time.Duration
+	Run     func(ctx context.Context) error
+}
+
+// OnStart registers a hook run by Run before the server starts listening. A
+// failing start hook aborts Run, which then runs the shutdown hooks.
+func (h *Handler) OnStart(hook Hook) {
+	h.startHooks = append(h.startHooks, hook)
+}
+
+// OnShutdown registers a hook run after the servers are closed, within the
+// shutdown grace period. Every shutdown hook runs, even when others fail.
+func (h *Handler) OnShutdown(hook Hook) {
+	h.shutdownHooks = append(h.shutdownHooks, hook)
+}
+
+func (h *Handler) runStartHooks(ctx context.Context) error {
+	for _, hook := range sortHooks(h.startHooks) {
+		if err := hook.run(ctx); err != nil {
+			return fmt.Errorf("start hook %s: %w", hook.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (h *Handler) runShutdownHooks(ctx context.Context) error {
+	var errs []error
+
+	hooks := slices.Clone(h.shutdownHooks)
+	slices.Reverse(hooks)
+
+	for _, hook := range sortHooks(hooks) {
+		if err := hook.run(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown hook %s: %w", hook.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// run returns as soon as the hook timeout or ctx expires, even if the hook
+// ignores its context, so a stuck hook cannot block the others.
+func (hook Hook) run(ctx context.Context) error {
+	if hook.Timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, hook.Timeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- hook.Run(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func sortHooks(hooks []Hook) []Hook {
+	sorted := append([]Hook(nil), hooks...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority < sorted[j].Priority
+	})
+
+	return sorted
+}
@@ -0,0 +1,90 @@
+package httpkit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHooks_Run(t *testing.T) {
+	t.Run("should run hooks by priority, then shutdown hooks in reverse registration order", func(t *testing.T) {
+		var calls []string
+
+		record := func(name string) func(context.Context) error {
+			return func(context.Context) error {
+				calls = append(calls, name)
+
+				return nil
+			}
+		}
+
+		handler := NewServer(WithPort(0))
+		handler.OnStart(Hook{Name: "consumer", Priority: 10, Run: record("start consumer")})
+		handler.OnStart(Hook{Name: "db", Run: record("start db")})
+		handler.OnShutdown(Hook{Name: "db", Priority: 10, Run: record("stop db")})
+		handler.OnShutdown(Hook{Name: "cache", Run: record("stop cache")})
+		handler.OnShutdown(Hook{Name: "consumer", Run: record("stop consumer")})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		handler.AddComponent(ComponentFunc(func(context.Context) error {
+			cancel()
+
+			return nil
+		}))
+
+		require.NoError(t, handler.Run(ctx))
+		assert.Equal(t, []string{"start db", "start consumer", "stop consumer", "stop cache", "stop db"}, calls)
+	})
+
+	t.Run("should aggregate shutdown errors and enforce hook timeouts", func(t *testing.T) {
+		errFlush := errors.New("flush failed")
+		closed := false
+
+		handler := NewServer(WithPort(0))
+		handler.OnShutdown(Hook{Name: "stuck", Timeout: 20 * time.Millisecond, Run: func(context.Context) error {
+			select {}
+		}})
+		handler.OnShutdown(Hook{Name: "tracer", Run: func(context.Context) error {
+			return errFlush
+		}})
+		handler.OnShutdown(Hook{Name: "db", Run: func(context.Context) error {
+			closed = true
+
+			return nil
+		}})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := handler.Run(ctx)
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.ErrorIs(t, err, errFlush)
+		assert.ErrorContains(t, err, "shutdown hook stuck")
+		assert.True(t, closed)
+	})
+
+	t.Run("should run shutdown hooks when a start hook fails", func(t *testing.T) {
+		errConnect := errors.New("connect failed")
+		cleaned := false
+
+		handler := NewServer(WithPort(0))
+		handler.OnStart(Hook{Name: "db", Run: func(context.Context) error {
+			return errConnect
+		}})
+		handler.OnShutdown(Hook{Name: "cleanup", Run: func(context.Context) error {
+			cleaned = true
+
+			return nil
+		}})
+
+		err := handler.Run(context.Background())
+
+		assert.ErrorIs(t, err, errConnect)
+		assert.True(t, cleaned)
+	})
+}
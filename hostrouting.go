@@ -0,0 +1,187 @@
+package httpkit
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/philippe-berto/httpkit/utils"
+)
+
+// probePaths are always served by the default host, so health checks work
+// whatever Host header they carry.
+var probePaths = []string{"/live", "/ready", "/startup", "/status"}
+
+func isProbe(r *http.Request) bool {
+	return slices.Contains(probePaths, routePath(r))
+}
+
+// routePath returns the path chi routes, relative to the mount point and
+// once StripSlashes removed its trailing slash.
+func routePath(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePath != "" {
+		return rctx.RoutePath
+	}
+
+	return r.URL.Path
+}
+
+type (
+	hostRoute struct {
+		pattern string
+		labels  []string
+		router  *chi.Mux
+	}
+
+	// hostRouter dispatches requests by Host to the SubDomains declaring one.
+	// Requests for other hosts fall back to the main router.
+	hostRouter struct {
+		routes []*hostRoute
+	}
+)
+
+func newHostRouter(subdomains []*SubDomain) (*hostRouter, error) {
+	byPattern := map[string]*hostRoute{}
+	hr := &hostRouter{}
+
+	for _, subdomain := range subdomains {
+		if subdomain.Host == "" {
+			continue
+		}
+
+		pattern := normalizeHost(subdomain.Host)
+
+		route, ok := byPattern[pattern]
+		if !ok {
+			labels := strings.Split(pattern, ".")
+			if slices.Contains(labels, "") {
+				return nil, fmt.Errorf("%w: invalid subdomain host %q", ErrInvalidConfig, subdomain.Host)
+			}
+
+			route = &hostRoute{pattern: pattern, labels: labels, router: chi.NewRouter()}
+			byPattern[pattern] = route
+			hr.routes = append(hr.routes, route)
+		}
+
+		prefix := subdomain.Domain
+		if prefix == "" {
+			prefix = "/"
+		}
+
+		route.router.Mount(prefix, subdomain.handler())
+	}
+
+	// Exact hosts win over wildcards, then the most specific wildcard wins.
+	sort.SliceStable(hr.routes, func(i, j int) bool {
+		return hr.routes[i].wildcards() < hr.routes[j].wildcards()
+	})
+
+	return hr, nil
+}
+
+func (r *hostRoute) wildcards() int {
+	count := 0
+
+	for _, label := range r.labels {
+		if label == "*" {
+			count++
+		}
+	}
+
+	return count
+}
+
+// match reports whether host matches the route, with the labels matched by
+// its wildcards. A wildcard matches exactly one label.
+func (r *hostRoute) match(host []string) ([]string, bool) {
+	if len(host) != len(r.labels) {
+		return nil, false
+	}
+
+	var wildcards []string
+
+	for i, label := range r.labels {
+		switch label {
+		case "*":
+			wildcards = append(wildcards, host[i])
+		case host[i]:
+		default:
+			return nil, false
+		}
+	}
+
+	return wildcards, true
+}
+
+func (hr *hostRouter) route(pattern string) *hostRoute {
+	for _, route := range hr.routes {
+		if route.pattern == pattern {
+			return route
+		}
+	}
+
+	return nil
+}
+
+// matchHost records the host route of the request for the instrumentation,
+// which runs before dispatch. It replaces the match of an outer Handler this
+// one is mounted in, whose routes it does not know.
+func (hr *hostRouter) matchHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := strings.Split(normalizeHost(utils.Host(r)), ".")
+		ctx := utils.ContextWithoutHostMatch(r.Context())
+
+		for _, route := range hr.routes {
+			if wildcards, ok := route.match(host); ok {
+				ctx = utils.ContextWithHostMatch(ctx, utils.HostMatch{Pattern: route.pattern, Wildcards: wildcards, Routes: route.router})
+
+				break
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// dispatch serves matched requests with the router of their host.
+func (hr *hostRouter) dispatch(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		match, ok := utils.HostMatchFromContext(r.Context())
+		if !ok || isProbe(r) {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		route := hr.route(match.Pattern)
+		if route == nil {
+			next.ServeHTTP(w, r)
+
+			return
+		}
+
+		route.router.ServeHTTP(w, r)
+	})
+}
+
+// HostWildcards returns the labels of the request host matched by the
+// wildcards of its SubDomain host, such as the tenant of
+// "*.tenant.example.com".
+func HostWildcards(r *http.Request) []string {
+	match, _ := utils.HostMatchFromContext(r.Context())
+
+	return match.Wildcards
+}
+
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
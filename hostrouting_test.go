@@ -0,0 +1,131 @@
+package httpkit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/philippe-berto/httpkit/utils"
+)
+
+func namedRouter(name string) chi.Router {
+	router := chi.NewRouter()
+	router.Get("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Join(append([]string{name}, HostWildcards(r)...), " ")))
+	})
+
+	return router
+}
+
+func hostRequest(handler http.Handler, host, path string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	r.Host = host
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestHostRouting_Dispatch(t *testing.T) {
+	handler := NewServer(WithSubDomains(
+		&SubDomain{Domain: "/default", Router: namedRouter("default")},
+		&SubDomain{Host: "*.tenant.example.com", Router: namedRouter("tenant")},
+		&SubDomain{Host: "admin.tenant.example.com", Router: namedRouter("admin")},
+		&SubDomain{Host: "API.example.com", Domain: "/v1", Router: namedRouter("api")},
+	))
+
+	t.Run("should dispatch exact hosts ahead of wildcards", func(t *testing.T) {
+		w := hostRequest(handler, "admin.tenant.example.com", "/whoami")
+
+		assert.Equal(t, "admin", w.Body.String())
+	})
+
+	t.Run("should capture wildcard labels", func(t *testing.T) {
+		w := hostRequest(handler, "acme.tenant.example.com:8443", "/whoami")
+
+		assert.Equal(t, "tenant acme", w.Body.String())
+	})
+
+	t.Run("should mount the router under Domain for its host", func(t *testing.T) {
+		assert.Equal(t, "api", hostRequest(handler, "api.example.com", "/v1/whoami").Body.String())
+		assert.Equal(t, http.StatusNotFound, hostRequest(handler, "api.example.com", "/whoami").Code)
+	})
+
+	t.Run("should fall back to the default host", func(t *testing.T) {
+		assert.Equal(t, "default", hostRequest(handler, "other.example.com", "/default/whoami").Body.String())
+		assert.Equal(t, http.StatusNotFound, hostRequest(handler, "a.b.tenant.example.com", "/whoami").Code)
+		assert.Equal(t, http.StatusNotFound, hostRequest(handler, "acme.tenant.example.com", "/default/whoami").Code)
+	})
+
+	t.Run("should serve probes on every host", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, hostRequest(handler, "acme.tenant.example.com", "/live").Code)
+		assert.Equal(t, http.StatusOK, hostRequest(handler, "api.example.com", "/ready").Code)
+		assert.Equal(t, http.StatusOK, hostRequest(handler, "api.example.com", "/ready/").Code)
+	})
+
+	t.Run("should ignore the host route of an outer Handler", func(t *testing.T) {
+		inner := NewServer(WithSubDomains(
+			&SubDomain{Domain: "/", Router: namedRouter("inner")},
+			&SubDomain{Host: "api.internal", Router: namedRouter("internal")},
+		))
+		outer := NewServer(WithSubDomains(&SubDomain{Host: "*.tenant.example.com", Domain: "/inner", Router: inner.Router}))
+
+		assert.Equal(t, "inner", hostRequest(outer, "acme.tenant.example.com", "/inner/whoami").Body.String())
+
+		r := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		r = r.WithContext(utils.ContextWithHostMatch(r.Context(), utils.HostMatch{Pattern: "*.tenant.example.com"}))
+		w := httptest.NewRecorder()
+		inner.ServeHTTP(w, r)
+		assert.Equal(t, "inner", w.Body.String())
+	})
+}
+
+func TestHostRouting_Instrumentation(t *testing.T) {
+	t.Run("should expose the host pattern before dispatch", func(t *testing.T) {
+		var pattern string
+
+		handler := NewServer(
+			WithSubDomains(&SubDomain{Host: "*.tenant.example.com", Router: namedRouter("tenant")}),
+			WithMiddleware(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					match, _ := utils.HostMatchFromContext(r.Context())
+					pattern = match.Pattern
+					next.ServeHTTP(w, r)
+				})
+			}),
+		)
+
+		hostRequest(handler, "acme.tenant.example.com", "/whoami")
+
+		assert.Equal(t, "*.tenant.example.com", pattern)
+	})
+
+	t.Run("should match the host forwarded by trusted proxies", func(t *testing.T) {
+		handler := NewServer(
+			WithTrustedProxies("192.0.2.0/24"),
+			WithSubDomains(&SubDomain{Host: "api.example.com", Router: namedRouter("api")}),
+		)
+
+		r := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		r.Header.Set("X-Forwarded-Host", "api.example.com")
+		r.Header.Set("X-Forwarded-For", "198.51.100.1")
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, "api", w.Body.String())
+	})
+}
+
+func TestHostRouting_InvalidHost(t *testing.T) {
+	t.Run("should fail to start with an invalid host", func(t *testing.T) {
+		handler := NewServer(WithSubDomains(&SubDomain{Host: "api..example.com", Router: namedRouter("api")}))
+
+		assert.ErrorIs(t, handler.Start(), ErrInvalidConfig)
+	})
+}
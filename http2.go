@@ -0,0 +1,57 @@
+package httpkit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+const (
+	minHTTP2FrameSize = 1 << 14
+	maxHTTP2FrameSize = 1<<24 - 1
+)
+
+type HTTP2Config struct {
+	// H2C serves HTTP/2 over cleartext connections, with prior knowledge or
+	// through the HTTP/1.1 Upgrade mechanism.
+	H2C                  bool          `env:"HTTP_H2C"                     envDefault:"false" yaml:"h2c"`
+	MaxConcurrentStreams uint32        `env:"HTTP2_MAX_CONCURRENT_STREAMS" yaml:"max_concurrent_streams"`
+	IdleTimeout          time.Duration `env:"HTTP2_IDLE_TIMEOUT"           yaml:"idle_timeout"`
+	MaxReadFrameSize     uint32        `env:"HTTP2_MAX_READ_FRAME_SIZE"    yaml:"max_read_frame_size"`
+}
+
+func (c HTTP2Config) validate() error {
+	var errs []error
+
+	if c.IdleTimeout < 0 {
+		errs = append(errs, fmt.Errorf("%w: http2.idle_timeout must not be negative, got %s", ErrInvalidConfig, c.IdleTimeout))
+	}
+
+	if c.MaxReadFrameSize != 0 && (c.MaxReadFrameSize < minHTTP2FrameSize || c.MaxReadFrameSize > maxHTTP2FrameSize) {
+		errs = append(errs, fmt.Errorf("%w: http2.max_read_frame_size must be between %d and %d, got %d",
+			ErrInvalidConfig, minHTTP2FrameSize, maxHTTP2FrameSize, c.MaxReadFrameSize))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c HTTP2Config) server() *http2.Server {
+	return &http2.Server{
+		MaxConcurrentStreams: c.MaxConcurrentStreams,
+		IdleTimeout:          c.IdleTimeout,
+		MaxReadFrameSize:     c.MaxReadFrameSize,
+	}
+}
+
+// handler wraps next to accept h2c connections when enabled.
+func (c HTTP2Config) handler(next http.Handler) http.Handler {
+	if !c.H2C {
+		return next
+	}
+
+	return h2c.NewHandler(next, c.server())
+}
@@ -0,0 +1,65 @@
+package httpkit
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+
+	"github.com/philippe-berto/httpkit/utils"
+)
+
+func TestHTTP2_H2C(t *testing.T) {
+	handler := NewServer(WithHTTP2(HTTP2Config{H2C: true, MaxConcurrentStreams: 10}))
+	handler.Router.Get("/proto", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(utils.ProtocolVersion(r)))
+	})
+
+	addr := serveForTest(t, handler)
+
+	get := func(client *http.Client) string {
+		resp, err := client.Get("http://" + addr + "/proto")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		return string(body)
+	}
+
+	t.Run("should serve HTTP/2 with prior knowledge", func(t *testing.T) {
+		client := &http.Client{Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, addr)
+			},
+		}}
+
+		assert.Equal(t, "2", get(client))
+	})
+
+	t.Run("should keep serving HTTP/1.1", func(t *testing.T) {
+		assert.Equal(t, "1.1", get(&http.Client{}))
+	})
+}
+
+func TestHTTP2_Validate(t *testing.T) {
+	t.Run("should reject out of range frame sizes", func(t *testing.T) {
+		err := HTTP2Config{MaxReadFrameSize: 1024}.validate()
+
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	})
+
+	t.Run("should reject h2c combined with tls", func(t *testing.T) {
+		cfg := Config{Port: 8080, HTTP2: HTTP2Config{H2C: true}, TLS: TLSConfig{CertFile: "tls.crt", KeyFile: "tls.key"}}
+
+		assert.ErrorContains(t, cfg.Validate(), "http2.h2c cannot be combined with tls")
+	})
+}
@@ -2,128 +2,368 @@ package httpkit
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/philippe-berto/logger"
+	"golang.org/x/net/http2"
 
+	"github.com/philippe-berto/httpkit/health"
 	"github.com/philippe-berto/httpkit/metrics"
 	"github.com/philippe-berto/httpkit/tracing"
 	"github.com/philippe-berto/httpkit/utils"
 )
 
+// CORSSelf, given as the allowed origins, allows the origin the request was
+// addressed to, as seen by the client through trusted proxies.
+const CORSSelf = "self"
+
 var CorsAllowOrigins string
 
 type (
 	Handler struct {
-		server *http.Server
-		Router *chi.Mux
+		server          *http.Server
+		Router          *chi.Mux
+		shutdownTimeout time.Duration
+		tls             *TLSConfig
+		http2           *HTTP2Config
+		log             *logger.Logger
+		adminServer     *http.Server
+		components      []Component
+		startHooks      []Hook
+		shutdownHooks   []Hook
+		health          *health.Registry
+		listenConfig    listenConfig
+		restart         *RestartConfig
+		proxyProtocol   *ProxyProtocolConfig
+		tracerProvider  *tracing.Provider
+		optionsErr      error
+		addrMu          sync.RWMutex
+		addr            net.Addr
+		started         atomic.Bool
+		gatesMu         sync.RWMutex
+		gates           []*ReadinessGate
+
+		drainDelay        time.Duration
+		shutdownObservers []func(ShutdownPhase)
+		draining          atomic.Bool
 	}
 
+	// SubDomain mounts Router under the Domain path prefix. With Host, it only
+	// serves requests for that host, such as "api.example.com" or
+	// "*.tenant.example.com" where each "*" matches one label, and Domain
+	// becomes optional. Requests for other hosts use the SubDomains without
+	// Host.
+	//
+	// The other fields set the policy of the SubDomain, zero values inheriting
+	// the Handler's.
 	SubDomain struct {
 		Domain string
+		Host   string
 		Router chi.Router
+
+		// Auth runs before Middlewares, such as RequireClientIdentity.
+		Auth        func(http.Handler) http.Handler
+		Middlewares []func(http.Handler) http.Handler
+		// CORS replaces the policy set by WithCORS. Enable false turns CORS
+		// off for the SubDomain.
+		CORS *CORSConfig
+		// MaxBodyBytes replaces the body limit set by WithLimits.
+		MaxBodyBytes   int64
+		DisableTracing bool
+		DisableMetrics bool
 	}
 )
 
+// New builds a Handler from positional arguments.
+//
+// Deprecated: use NewServer with options instead.
 func New(port int, tracerEnable, metricsEnable, setCors bool, corsAllowOrigins string, subdomains ...*SubDomain) *Handler {
-	router := chi.NewRouter()
+	opts := []Option{WithPort(port), WithSubDomains(subdomains...)}
 
-	router.Use(chimiddleware.StripSlashes)
-
-	if metricsEnable {
-		router.Use(metrics.MetricsMiddleware)
+	if tracerEnable {
+		opts = append(opts, WithTracing())
 	}
 
-	if tracerEnable {
-		router.Use(tracing.TracingMiddleware)
+	if metricsEnable {
+		opts = append(opts, WithMetrics())
 	}
 
 	if setCors {
-		CorsAllowOrigins = corsAllowOrigins
-		router.Use(cors)
+		opts = append(opts, WithCORS(corsAllowOrigins))
 	}
 
-	router.Use(chimiddleware.RealIP)
-	router.NotFoundHandler()
-	router.MethodNotAllowedHandler()
+	return NewServer(opts...)
+}
 
-	router.Get("/", GetStatus)
-	router.Get("/ready", GetStatus)
-	router.Get("/status", GetStatus)
+// NewEmpty builds a Handler exposing only the status routes.
+//
+// Deprecated: use NewServer with options instead.
+func NewEmpty(port int, tracerEnable, metricsEnable bool) *Handler {
+	opts := []Option{WithPort(port)}
 
-	for _, subdomain := range subdomains {
-		router.Mount(subdomain.Domain, subdomain.Router)
+	if tracerEnable {
+		opts = append(opts, WithTracing())
 	}
 
-	return &Handler{
-		Router: router,
-		server: &http.Server{
-			Addr:    fmt.Sprintf(":%d", port),
-			Handler: router,
-		},
+	if metricsEnable {
+		opts = append(opts, WithMetrics())
 	}
+
+	return NewServer(opts...)
 }
 
-func NewEmpty(port, tracerEnable bool, metricsEnable bool) *Handler {
+// NewServer builds a Handler configured by the given options. Without options
+// it serves only the status routes on the default port.
+func NewServer(opts ...Option) *Handler {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	router := chi.NewRouter()
 
+	h := &Handler{
+		Router:            router,
+		shutdownTimeout:   o.shutdownTimeout,
+		drainDelay:        o.drainDelay,
+		shutdownObservers: o.shutdownObservers,
+		tls:               o.tls,
+		http2:             o.http2,
+		log:               o.log,
+		components:        o.components,
+		health:            health.NewRegistry(),
+		listenConfig:      o.listen,
+		restart:           o.restart,
+		proxyProtocol:     o.proxyProtocol,
+	}
+
+	h.started.Store(!o.manualStartup)
+
+	if o.admin != nil {
+		h.adminServer = h.newAdminServer(*o.admin)
+	}
+
+	subdomains, err := h.mountModules(o.subdomains, o.modules)
+	if err != nil {
+		h.optionsErr = err
+	}
+
+	resolver, err := utils.NewProxyResolver(o.trustedProxies)
+	if err != nil {
+		h.optionsErr = errors.Join(h.optionsErr, fmt.Errorf("%w: trusted_proxies: %w", ErrInvalidConfig, err))
+		resolver = &utils.ProxyResolver{}
+	}
+
+	hosts, err := newHostRouter(subdomains)
+	if err != nil {
+		h.optionsErr = errors.Join(h.optionsErr, err)
+		hosts = &hostRouter{}
+	}
+
+	if o.propagators == nil {
+		o.propagators = tracing.DefaultPropagators()
+	}
+
+	propagator, err := tracing.NewPropagator(o.propagators...)
+	if err != nil {
+		h.optionsErr = errors.Join(h.optionsErr, fmt.Errorf("%w: %w", ErrInvalidConfig, err))
+	}
+
 	router.Use(chimiddleware.StripSlashes)
+	router.Use(resolver.Middleware)
 
-	if tracerEnable {
-		router.Use(tracing.TracingMiddleware)
+	router.Use(hosts.matchHost)
+
+	router.Use(newSubdomainResolver(subdomains).middleware)
+
+	if o.metrics {
+		router.Use(unlessSubdomain(func(s *SubDomain) bool { return s.DisableMetrics }, metrics.MetricsMiddleware))
 	}
 
-	if metricsEnable {
-		router.Use(metrics.MetricsMiddleware)
+	if o.tracing {
+		// The options given to WithTracing may override the propagator.
+		tracingOptions := append([]tracing.Option{tracing.WithPropagator(propagator)}, o.tracingOptions...)
+		if err := tracing.CheckOptions(tracingOptions...); err != nil {
+			h.optionsErr = errors.Join(h.optionsErr, fmt.Errorf("%w: %w", ErrInvalidConfig, err))
+		}
+
+		router.Use(unlessSubdomain(func(s *SubDomain) bool { return s.DisableTracing }, tracing.New(tracingOptions...)))
+	}
+
+	// Rejected bodies are still measured and traced.
+	router.Use(subdomainBodyLimit(o.limits.MaxBodyBytes))
+
+	if o.tls != nil && o.tls.MutualTLS() {
+		router.Use(ClientIdentityMiddleware)
+	}
+
+	var globalCORS *CORSConfig
+	if o.cors {
+		CorsAllowOrigins = o.corsAllowOrigins
+		globalCORS = &CORSConfig{Enable: true, AllowOrigins: o.corsAllowOrigins}
+	}
+
+	if globalCORS != nil || slices.ContainsFunc(subdomains, func(s *SubDomain) bool { return s.CORS != nil }) {
+		router.Use(subdomainCORS(globalCORS))
+	}
+
+	router.Use(o.middlewares...)
+
+	if len(hosts.routes) > 0 {
+		router.Use(hosts.dispatch)
 	}
 
-	router.Use(chimiddleware.RealIP)
 	router.NotFoundHandler()
 	router.MethodNotAllowedHandler()
 
 	router.Get("/", GetStatus)
-	router.Get("/ready", GetStatus)
-	router.Get("/status", GetStatus)
+	router.Get("/live", GetStatus)
+	router.Get("/startup", h.getStartup)
+	router.Get("/ready", h.getReady)
+	router.Get("/status", h.getStatus)
 
-	return &Handler{
-		Router: router,
-		server: &http.Server{
-			Addr:    fmt.Sprintf(":%d", port),
-			Handler: router,
-		},
+	for _, subdomain := range subdomains {
+		if subdomain.Host == "" {
+			router.Mount(subdomain.Domain, subdomain.handler())
+		}
+	}
+
+	var handler http.Handler = router
+	if o.http2 != nil {
+		handler = o.http2.handler(router)
 	}
+
+	h.server = &http.Server{
+		Addr:              fmt.Sprintf(":%d", o.port),
+		Handler:           handler,
+		ReadHeaderTimeout: o.timeouts.ReadHeader,
+		ReadTimeout:       o.timeouts.Read,
+		WriteTimeout:      o.timeouts.Write,
+		IdleTimeout:       o.timeouts.Idle,
+		MaxHeaderBytes:    o.limits.MaxHeaderBytes,
+	}
+
+	return h
 }
 
+// TracerProvider returns the provider NewFromConfig built from the tracing
+// exporter config, to create spans exported along with the requests. It is
+// nil for Handlers built otherwise.
+func (h *Handler) TracerProvider() *tracing.Provider {
+	return h.tracerProvider
+}
+
+// Err reports the invalid options given to NewServer, such as conflicting
+// SubDomain or Module mounts, which are left out of the router. Run and Start
+// return it before serving.
+func (h *Handler) Err() error {
+	return h.optionsErr
+}
+
+// Start serves HTTP until the server is shut down. Prefer Run, which also
+// manages the admin server, components and shutdown.
 func (h *Handler) Start() error {
-	err := h.server.ListenAndServe()
-	if err != nil && err != http.ErrServerClosed {
+	ln, err := h.listen()
+	if err != nil {
+		return err
+	}
+
+	return h.serve(ln)
+}
+
+func (h *Handler) serve(ln net.Listener) error {
+	if h.proxyProtocol != nil {
+		proxyLn, err := h.proxyProtocol.listener(ln, h.server.ReadHeaderTimeout)
+		if err != nil {
+			_ = ln.Close()
+
+			return err
+		}
+
+		ln = proxyLn
+	}
+
+	var err error
+
+	if h.tls != nil {
+		err = h.serveTLS(ln)
+	} else {
+		err = h.server.Serve(ln)
+	}
+
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return err
 	}
 
 	return nil
 }
 
+func (h *Handler) serveTLS(ln net.Listener) error {
+	reloader, err := newCertReloader(h.tls.CertFile, h.tls.KeyFile)
+	if err != nil {
+		_ = ln.Close()
+
+		return err
+	}
+
+	tlsConfig, err := h.tls.build(reloader)
+	if err != nil {
+		_ = ln.Close()
+
+		return err
+	}
+
+	h.server.TLSConfig = tlsConfig
+
+	if h.http2 != nil {
+		if err := http2.ConfigureServer(h.server, h.http2.server()); err != nil {
+			_ = ln.Close()
+
+			return err
+		}
+	}
+
+	ctx, stopWatch := context.WithCancel(context.Background())
+	h.server.RegisterOnShutdown(stopWatch)
+
+	go reloader.watch(ctx, h.tls.ReloadInterval, func(err error) {
+		if h.log != nil {
+			h.log.WithFields(logger.Fields{"error": err}).Error("Failed to reload TLS certificate")
+		}
+	})
+
+	return h.server.ServeTLS(ln, "", "")
+}
+
+// GracefulShutdown waits for SIGINT or SIGTERM and then shuts the server down,
+// waiting up to gracefulTimeout seconds for in-flight requests. A non-positive
+// gracefulTimeout uses the timeout configured with WithShutdownTimeout.
+//
+// Deprecated: use Run, which can also be stopped by cancelling its context.
 func (h *Handler) GracefulShutdown(ctx context.Context, gracefulTimeout int) error {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
 	<-quit
 
-	ctx, shutdown := context.WithTimeout(ctx, time.Duration(gracefulTimeout)*time.Second)
-	defer shutdown()
-
-	err := h.server.Shutdown(ctx)
-	if err != nil {
-		return err
+	timeout := h.shutdownTimeout
+	if gracefulTimeout > 0 {
+		timeout = time.Duration(gracefulTimeout) * time.Second
 	}
 
-	return nil
+	return h.shutdown(ctx, timeout)
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -137,19 +377,26 @@ func GetStatus(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func cors(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", CorsAllowOrigins)
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, User-Address, Token")
-		w.Header().Set("Access-Control-Max-Age", "3600")
+func cors(allowOrigins string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := allowOrigins
+			if origin == CORSSelf {
+				origin = utils.Scheme(r) + "://" + utils.Host(r)
+			}
 
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusNoContent)
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, User-Address, Token")
+			w.Header().Set("Access-Control-Max-Age", "3600")
 
-			return
-		}
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
 
-		next.ServeHTTP(w, r)
-	})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
 }
@@ -0,0 +1,213 @@
+package httpkit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/philippe-berto/httpkit/health"
+	"github.com/philippe-berto/httpkit/tracing"
+	"github.com/philippe-berto/httpkit/utils"
+)
+
+func TestHttpkit_NewServer(t *testing.T) {
+	t.Run("should serve status routes without options", func(t *testing.T) {
+		handler := NewServer()
+
+		responseWriter := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, "/ready", nil)
+		require.NoError(t, err)
+
+		handler.ServeHTTP(responseWriter, request)
+
+		assert.Equal(t, http.StatusOK, responseWriter.Code)
+		assert.Equal(t, ":8080", handler.server.Addr)
+	})
+
+	t.Run("should apply port, cors, subdomains and middlewares", func(t *testing.T) {
+		sub := chi.NewRouter()
+		sub.Get("/ping", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		})
+
+		handler := NewServer(
+			WithPort(9090),
+			WithCORS("https://example.com"),
+			WithSubDomains(&SubDomain{Domain: "/api", Router: sub}),
+			WithMiddleware(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("X-Test", "true")
+					next.ServeHTTP(w, r)
+				})
+			}),
+		)
+
+		responseWriter := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, "/api/ping", nil)
+		require.NoError(t, err)
+
+		handler.ServeHTTP(responseWriter, request)
+
+		assert.Equal(t, http.StatusTeapot, responseWriter.Code)
+		assert.Equal(t, "https://example.com", responseWriter.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", responseWriter.Header().Get("X-Test"))
+		assert.Equal(t, ":9090", handler.server.Addr)
+	})
+
+	t.Run("should keep legacy constructors working", func(t *testing.T) {
+		assert.Equal(t, ":8081", New(8081, false, false, false, "").server.Addr)
+		assert.Equal(t, ":8082", NewEmpty(8082, false, false).server.Addr)
+	})
+
+	t.Run("should report a sampling policy the provider does not honor", func(t *testing.T) {
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.NeverSample()))
+
+		handler := NewServer(WithTracing(
+			tracing.WithTracerProvider(provider),
+			tracing.WithSampling(tracing.SamplingPolicy{DebugHeader: "X-Debug-Trace", SampleErrors: true}),
+		))
+
+		assert.ErrorIs(t, handler.Err(), ErrInvalidConfig)
+		assert.ErrorIs(t, handler.Err(), tracing.ErrSamplingUnsupported)
+	})
+}
+
+func TestHttpkit_HealthChecks(t *testing.T) {
+	t.Run("should fail readiness and report when a critical check fails", func(t *testing.T) {
+		handler := NewServer()
+		handler.RegisterHealthCheck(health.CheckerFunc("db", func(context.Context) error {
+			return errors.New("connection refused")
+		}))
+		handler.RegisterHealthCheck(health.CheckerFunc("cache", func(context.Context) error {
+			return nil
+		}), health.NonCritical())
+
+		ready := serveRecorded(t, handler, "/ready")
+		assert.Equal(t, http.StatusServiceUnavailable, ready.Code)
+
+		status := serveRecorded(t, handler, "/status")
+		assert.Equal(t, http.StatusServiceUnavailable, status.Code)
+		assert.Contains(t, status.Body.String(), `"error":"connection refused"`)
+		assert.Contains(t, status.Body.String(), `"name":"cache","status":"up"`)
+	})
+
+	t.Run("should stay ready when only non critical checks fail", func(t *testing.T) {
+		handler := NewServer()
+		handler.RegisterHealthCheck(health.CheckerFunc("cache", func(context.Context) error {
+			return errors.New("timeout")
+		}), health.NonCritical())
+
+		assert.Equal(t, http.StatusOK, serveRecorded(t, handler, "/ready").Code)
+		assert.Contains(t, serveRecorded(t, handler, "/status").Body.String(), `"status":"degraded"`)
+	})
+}
+
+func TestHttpkit_TrustedProxies(t *testing.T) {
+	t.Run("should resolve the client before the user middlewares", func(t *testing.T) {
+		var clientIP string
+
+		handler := NewServer(WithTrustedProxies("192.0.2.0/24"), WithMiddleware(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				clientIP = utils.ClientIP(r)
+				next.ServeHTTP(w, r)
+			})
+		}))
+
+		r := httptest.NewRequest(http.MethodGet, "/live", nil)
+		r.Header.Set("X-Forwarded-For", "198.51.100.1")
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+
+		assert.Equal(t, "198.51.100.1", clientIP)
+	})
+
+	t.Run("should not trust X-Forwarded-For by default", func(t *testing.T) {
+		var remoteAddr string
+
+		handler := NewServer()
+		handler.Router.Get("/addr", func(w http.ResponseWriter, r *http.Request) {
+			remoteAddr = r.RemoteAddr
+		})
+
+		r := httptest.NewRequest(http.MethodGet, "/addr", nil)
+		r.Header.Set("X-Forwarded-For", "198.51.100.1")
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+
+		assert.Equal(t, "192.0.2.1:1234", remoteAddr)
+	})
+
+	t.Run("should fail to start with invalid trusted proxies", func(t *testing.T) {
+		err := NewServer(WithTrustedProxies("not-a-cidr")).Start()
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	})
+
+	t.Run("should allow the forwarded origin with CORS self", func(t *testing.T) {
+		handler := NewServer(WithTrustedProxies("192.0.2.0/24"), WithCORS(CORSSelf))
+
+		r := httptest.NewRequest(http.MethodGet, "/live", nil)
+		r.Header.Set("X-Forwarded-Proto", "https")
+		r.Header.Set("X-Forwarded-Host", "api.example.com")
+		r.Header.Set("X-Forwarded-For", "198.51.100.1")
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, "https://api.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
+
+func TestHttpkit_Propagators(t *testing.T) {
+	serveTraced := func(handler *Handler, headers map[string]string) trace.SpanContext {
+		var spanContext trace.SpanContext
+
+		handler.Router.Get("/traced", func(w http.ResponseWriter, r *http.Request) {
+			spanContext = trace.SpanContextFromContext(r.Context())
+		})
+
+		r := httptest.NewRequest(http.MethodGet, "/traced", nil)
+		for key, value := range headers {
+			r.Header.Set(key, value)
+		}
+
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+
+		return spanContext
+	}
+
+	t.Run("should continue W3C traces by default without touching the global propagator", func(t *testing.T) {
+		previous := otel.GetTextMapPropagator()
+		provider := sdktrace.NewTracerProvider()
+
+		spanContext := serveTraced(NewServer(WithTracing(tracing.WithTracerProvider(provider))), map[string]string{
+			"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		})
+
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spanContext.TraceID().String())
+		assert.Equal(t, previous, otel.GetTextMapPropagator())
+	})
+
+	t.Run("should keep the propagators of each Handler", func(t *testing.T) {
+		provider := sdktrace.NewTracerProvider()
+
+		b3Handler := NewServer(WithTracing(tracing.WithTracerProvider(provider)), WithPropagators(tracing.PropagatorB3))
+		w3cHandler := NewServer(WithTracing(tracing.WithTracerProvider(provider)))
+
+		headers := map[string]string{"b3": "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1"}
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", serveTraced(b3Handler, headers).TraceID().String())
+		assert.NotEqual(t, "4bf92f3577b34da6a3ce929d0e0e4736", serveTraced(w3cHandler, headers).TraceID().String())
+	})
+
+	t.Run("should fail to start with an unknown propagator", func(t *testing.T) {
+		err := NewServer(WithPropagators("xray")).Start()
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+		assert.ErrorIs(t, err, tracing.ErrUnknownPropagator)
+	})
+}
@@ -0,0 +1,235 @@
+package httpkit
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/philippe-berto/httpkit/metrics"
+)
+
+const (
+	// ShutdownDraining is entered as soon as shutdown starts: /ready answers
+	// 503 while the server keeps serving for the drain delay.
+	ShutdownDraining ShutdownPhase = "draining"
+	// ShutdownClosing is entered when the server stops accepting connections
+	// and waits for in-flight requests.
+	ShutdownClosing ShutdownPhase = "closing"
+	// ShutdownStopped is entered once the servers are closed.
+	ShutdownStopped ShutdownPhase = "stopped"
+)
+
+type (
+	ShutdownPhase string
+
+	// Component is a background task run alongside the server by Run. Run
+	// must block until ctx is cancelled or the component fails.
+	Component interface {
+		Run(ctx context.Context) error
+	}
+
+	ComponentFunc func(ctx context.Context) error
+)
+
+func (f ComponentFunc) Run(ctx context.Context) error {
+	return f(ctx)
+}
+
+// AddComponent registers a background component started by Run. It must be
+// called before Run.
+func (h *Handler) AddComponent(component Component) {
+	h.components = append(h.components, component)
+}
+
+// Run runs the start hooks, then serves HTTP, the admin server and every
+// registered component until ctx is cancelled, SIGINT or SIGTERM is received
+// or one of them fails. It then shuts everything down, runs the shutdown hooks
+// and returns the first error encountered. With WithGracefulRestart, a
+// successful handoff on SIGUSR2 also shuts it down.
+func (h *Handler) Run(ctx context.Context) error {
+	ctx, stopSignals := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	restarts, stopRestarts := h.restartSignals()
+	defer stopRestarts()
+
+	if h.optionsErr != nil {
+		return h.optionsErr
+	}
+
+	if err := h.runStartHooks(ctx); err != nil {
+		return errors.Join(err, h.abortStart(ctx))
+	}
+
+	ln, err := h.listen()
+	if err != nil {
+		return errors.Join(err, h.abortStart(ctx))
+	}
+
+	var adminLn net.Listener
+
+	if h.adminServer != nil {
+		if adminLn, err = h.listenAdmin(); err != nil {
+			_ = ln.Close()
+
+			return errors.Join(err, h.abortStart(ctx))
+		}
+	}
+
+	componentsCtx, stopComponents := context.WithCancel(ctx)
+	defer stopComponents()
+
+	var wg sync.WaitGroup
+
+	servers := []func() error{func() error { return h.serve(ln) }}
+	if adminLn != nil {
+		servers = append(servers, func() error { return serveHTTP(h.adminServer, adminLn) })
+	}
+
+	serverErrs := make(chan error, len(servers))
+	componentErrs := make(chan error, len(h.components))
+
+	for _, serve := range servers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			serverErrs <- serve()
+		}()
+	}
+
+	for _, component := range h.components {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			componentErrs <- component.Run(componentsCtx)
+		}()
+	}
+
+	signalRestartReady()
+
+	// Wait until something asks to stop. A server returning, even without
+	// error, means it was closed and the whole handler has to go down.
+	var (
+		firstErr   error
+		restarting bool
+		restarted  = make(chan error, 1)
+	)
+
+wait:
+	for {
+		select {
+		case <-ctx.Done():
+			break wait
+		case <-restarts:
+			if !restarting {
+				restarting = true
+
+				go func() { restarted <- h.handoff(ln, adminLn) }()
+			}
+		case err := <-restarted:
+			restarting = false
+			h.logRestart(err)
+
+			if err == nil {
+				break wait
+			}
+		case firstErr = <-serverErrs:
+			break wait
+		case firstErr = <-componentErrs:
+			if firstErr != nil {
+				break wait
+			}
+		}
+	}
+
+	stopComponents()
+
+	shutdownErr := h.shutdown(context.WithoutCancel(ctx), h.shutdownTimeout)
+
+	wg.Wait()
+	close(serverErrs)
+	close(componentErrs)
+
+	for _, errs := range []chan error{serverErrs, componentErrs} {
+		for err := range errs {
+			if firstErr == nil && !errors.Is(err, context.Canceled) {
+				firstErr = err
+			}
+		}
+	}
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	return shutdownErr
+}
+
+// abortStart runs the shutdown hooks when Run fails before serving, so
+// resources acquired by the start hooks are released.
+func (h *Handler) abortStart(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), h.shutdownTimeout)
+	defer cancel()
+
+	return h.runShutdownHooks(ctx)
+}
+
+// shutdown first reports the handler as not ready for the drain delay, so
+// load balancers stop routing to it, then stops the servers and runs the
+// shutdown hooks, all within timeout.
+func (h *Handler) shutdown(ctx context.Context, timeout time.Duration) error {
+	start := time.Now()
+
+	h.draining.Store(true)
+	h.notifyShutdown(ShutdownDraining)
+
+	if h.drainDelay > 0 {
+		drain := time.NewTimer(h.drainDelay)
+
+		select {
+		case <-drain.C:
+		case <-ctx.Done():
+			drain.Stop()
+		}
+	}
+
+	h.notifyShutdown(ShutdownClosing)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	errs := []error{h.server.Shutdown(ctx)}
+
+	if h.adminServer != nil {
+		errs = append(errs, h.adminServer.Shutdown(ctx))
+	}
+
+	errs = append(errs, h.runShutdownHooks(ctx))
+
+	metrics.ObserveShutdown(time.Since(start))
+	h.notifyShutdown(ShutdownStopped)
+
+	return errors.Join(errs...)
+}
+
+func (h *Handler) notifyShutdown(phase ShutdownPhase) {
+	for _, observe := range h.shutdownObservers {
+		observe(phase)
+	}
+}
+
+func serveHTTP(server *http.Server, ln net.Listener) error {
+	err := server.Serve(ln)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
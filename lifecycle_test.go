@@ -0,0 +1,132 @@
+package httpkit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runForTest(handler *Handler, ctx context.Context) chan error {
+	done := make(chan error, 1)
+
+	go func() {
+		done <- handler.Run(ctx)
+	}()
+
+	return done
+}
+
+func TestLifecycle_Run(t *testing.T) {
+	t.Run("should stop components and return when the context is cancelled", func(t *testing.T) {
+		started := make(chan struct{})
+		stopped := make(chan struct{})
+
+		handler := NewServer(WithPort(0), WithComponents(ComponentFunc(func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			close(stopped)
+
+			return ctx.Err()
+		})))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := runForTest(handler, ctx)
+
+		<-started
+		cancel()
+
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("Run did not return after cancellation")
+		}
+
+		assert.True(t, isClosed(stopped))
+	})
+
+	t.Run("should shut down and return the first component error", func(t *testing.T) {
+		errBoom := errors.New("boom")
+
+		handler := NewServer(WithPort(0))
+		handler.AddComponent(ComponentFunc(func(ctx context.Context) error {
+			return errBoom
+		}))
+		handler.AddComponent(ComponentFunc(func(ctx context.Context) error {
+			<-ctx.Done()
+
+			return errors.New("stopped after boom")
+		}))
+
+		select {
+		case err := <-runForTest(handler, context.Background()):
+			assert.ErrorIs(t, err, errBoom)
+		case <-time.After(time.Second):
+			t.Fatal("Run did not return after a component failed")
+		}
+	})
+
+	t.Run("should return listener errors", func(t *testing.T) {
+		handler := NewServer(WithPort(-1))
+
+		assert.Error(t, handler.Run(context.Background()))
+	})
+}
+
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestLifecycle_Drain(t *testing.T) {
+	t.Run("should report not ready during the drain delay", func(t *testing.T) {
+		phases := make(chan ShutdownPhase, 3)
+
+		handler := NewServer(
+			WithPort(0),
+			WithDrainDelay(200*time.Millisecond),
+			WithShutdownObserver(func(phase ShutdownPhase) {
+				phases <- phase
+			}),
+		)
+
+		assert.Equal(t, http.StatusOK, serveRecorded(t, handler, "/ready").Code)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := runForTest(handler, ctx)
+
+		cancel()
+		require.Equal(t, ShutdownDraining, <-phases)
+
+		responseWriter := serveRecorded(t, handler, "/ready")
+		assert.Equal(t, http.StatusServiceUnavailable, responseWriter.Code)
+		assert.Contains(t, responseWriter.Body.String(), "draining")
+		assert.Equal(t, http.StatusOK, serveRecorded(t, handler, "/status").Code)
+
+		require.NoError(t, <-done)
+		assert.Equal(t, ShutdownClosing, <-phases)
+		assert.Equal(t, ShutdownStopped, <-phases)
+	})
+}
+
+func serveRecorded(t *testing.T, handler http.Handler, path string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	responseWriter := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, path, nil)
+	require.NoError(t, err)
+
+	handler.ServeHTTP(responseWriter, request)
+
+	return responseWriter
+}
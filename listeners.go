@@ -0,0 +1,204 @@
+package httpkit
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+)
+
+var ErrNoSystemdSocket = errors.New("no systemd socket passed to the process")
+
+type (
+	UnixSocketConfig struct {
+		Path string `env:"HTTP_UNIX_SOCKET"       yaml:"path"`
+		// Mode is the octal file mode of the socket, such as "0660".
+		Mode string `env:"HTTP_UNIX_SOCKET_MODE"  yaml:"mode"`
+		// User and Group own the socket, by name or numeric id. Empty keeps
+		// the process owner.
+		User  string `env:"HTTP_UNIX_SOCKET_USER"  yaml:"user"`
+		Group string `env:"HTTP_UNIX_SOCKET_GROUP" yaml:"group"`
+	}
+
+	SystemdSocketConfig struct {
+		Enable bool `env:"HTTP_SYSTEMD_SOCKET"      envDefault:"false" yaml:"enable"`
+		// Name selects the socket by its FileDescriptorName. Empty takes the
+		// first one.
+		Name string `env:"HTTP_SYSTEMD_SOCKET_NAME" yaml:"name"`
+	}
+
+	listenConfig struct {
+		listener net.Listener
+		unix     *UnixSocketConfig
+		systemd  *SystemdSocketConfig
+	}
+)
+
+func (c UnixSocketConfig) validate() error {
+	if _, err := parseFileMode(c.Mode); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Addr returns the address the server listens on, or nil before it started.
+// It reveals the port picked for WithPort(0).
+func (h *Handler) Addr() net.Addr {
+	h.addrMu.RLock()
+	defer h.addrMu.RUnlock()
+
+	return h.addr
+}
+
+// listen prefers the listener inherited from a graceful restart over the
+// configured one.
+func (h *Handler) listen() (net.Listener, error) {
+	if h.optionsErr != nil {
+		return nil, h.optionsErr
+	}
+
+	var (
+		ln  net.Listener
+		err error
+	)
+
+	switch inheritedLn := inheritedListener(httpListenerName); {
+	case inheritedLn != nil:
+		ln = inheritedLn
+	case h.listenConfig.listener != nil:
+		ln = h.listenConfig.listener
+	case h.listenConfig.unix != nil:
+		ln, err = listenUnix(*h.listenConfig.unix)
+	case h.listenConfig.systemd != nil:
+		ln, err = systemdListener(h.listenConfig.systemd.Name)
+	default:
+		ln, err = net.Listen("tcp", h.server.Addr)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	h.addrMu.Lock()
+	h.addr = ln.Addr()
+	h.addrMu.Unlock()
+
+	return ln, nil
+}
+
+func (h *Handler) listenAdmin() (net.Listener, error) {
+	if ln := inheritedListener(adminListenerName); ln != nil {
+		return ln, nil
+	}
+
+	return net.Listen("tcp", h.adminServer.Addr)
+}
+
+func listenUnix(cfg UnixSocketConfig) (net.Listener, error) {
+	mode, err := parseFileMode(cfg.Mode)
+	if err != nil {
+		return nil, err
+	}
+
+	// A socket left by a crashed process would make Listen fail, but never
+	// remove anything that is not a socket.
+	if info, err := os.Lstat(cfg.Path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(cfg.Path); err != nil {
+			return nil, fmt.Errorf("removing stale unix socket: %w", err)
+		}
+	}
+
+	ln, err := net.Listen("unix", cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := setSocketPermissions(cfg, mode); err != nil {
+		_ = ln.Close()
+
+		return nil, err
+	}
+
+	return ln, nil
+}
+
+func setSocketPermissions(cfg UnixSocketConfig, mode os.FileMode) error {
+	if mode != 0 {
+		if err := os.Chmod(cfg.Path, mode); err != nil {
+			return fmt.Errorf("setting unix socket mode: %w", err)
+		}
+	}
+
+	if cfg.User == "" && cfg.Group == "" {
+		return nil
+	}
+
+	uid, gid := -1, -1
+
+	if cfg.User != "" {
+		u, err := lookupID(cfg.User, func(name string) (string, error) {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return "", err
+			}
+
+			return u.Uid, nil
+		})
+		if err != nil {
+			return fmt.Errorf("looking up unix socket user: %w", err)
+		}
+
+		uid = u
+	}
+
+	if cfg.Group != "" {
+		g, err := lookupID(cfg.Group, func(name string) (string, error) {
+			g, err := user.LookupGroup(name)
+			if err != nil {
+				return "", err
+			}
+
+			return g.Gid, nil
+		})
+		if err != nil {
+			return fmt.Errorf("looking up unix socket group: %w", err)
+		}
+
+		gid = g
+	}
+
+	if err := os.Chown(cfg.Path, uid, gid); err != nil {
+		return fmt.Errorf("setting unix socket owner: %w", err)
+	}
+
+	return nil
+}
+
+func lookupID(nameOrID string, lookup func(string) (string, error)) (int, error) {
+	if id, err := strconv.Atoi(nameOrID); err == nil {
+		return id, nil
+	}
+
+	id, err := lookup(nameOrID)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(id)
+}
+
+func parseFileMode(mode string) (os.FileMode, error) {
+	if mode == "" {
+		return 0, nil
+	}
+
+	parsed, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || parsed > 0o777 {
+		return 0, fmt.Errorf("%w: unix_socket.mode must be an octal permission such as 0660, got %q", ErrInvalidConfig, mode)
+	}
+
+	return os.FileMode(parsed), nil
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package httpkit
+
+import (
+	"net"
+)
+
+func systemdListener(string) (net.Listener, error) {
+	return nil, ErrNoSystemdSocket
+}
@@ -0,0 +1,56 @@
+package httpkit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListeners_WithListener(t *testing.T) {
+	t.Run("should serve on the given listener and expose its address", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		handler := NewServer(WithListener(ln))
+		assert.Nil(t, handler.Addr())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := runForTest(handler, ctx)
+
+		defer func() {
+			cancel()
+			require.NoError(t, <-done)
+		}()
+
+		require.Eventually(t, func() bool { return handler.Addr() != nil }, time.Second, 5*time.Millisecond)
+		assert.Equal(t, ln.Addr().String(), handler.Addr().String())
+
+		resp, err := http.Get("http://" + handler.Addr().String() + "/live")
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
+
+func TestListeners_ParseFileMode(t *testing.T) {
+	t.Run("should parse octal modes", func(t *testing.T) {
+		mode, err := parseFileMode("0660")
+		require.NoError(t, err)
+
+		assert.Equal(t, 0o660, int(mode))
+	})
+
+	t.Run("should reject invalid modes", func(t *testing.T) {
+		_, err := parseFileMode("rw-rw----")
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+
+		_, err = parseFileMode("7777")
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	})
+}
@@ -0,0 +1,56 @@
+//go:build unix
+
+package httpkit
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd, as defined
+// by sd_listen_fds(3).
+var listenFDsStart = 3
+
+func systemdListener(name string) (net.Listener, error) {
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, ErrNoSystemdSocket
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count < 1 {
+		return nil, ErrNoSystemdSocket
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	for i := range count {
+		fdName := ""
+		if i < len(names) {
+			fdName = names[i]
+		}
+
+		if name != "" && fdName != name {
+			continue
+		}
+
+		fd := listenFDsStart + i
+		syscall.CloseOnExec(fd)
+
+		file := os.NewFile(uintptr(fd), "systemd:"+fdName)
+
+		ln, err := net.FileListener(file)
+		_ = file.Close()
+
+		if err != nil {
+			return nil, fmt.Errorf("using systemd socket %d: %w", fd, err)
+		}
+
+		return ln, nil
+	}
+
+	return nil, fmt.Errorf("%w: no socket named %q", ErrNoSystemdSocket, name)
+}
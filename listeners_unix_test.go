@@ -0,0 +1,123 @@
+//go:build unix
+
+package httpkit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func unixClient(path string) *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+}
+
+func TestListeners_UnixSocket(t *testing.T) {
+	t.Run("should serve on a unix socket with the configured mode", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "httpkit.sock")
+
+		// A socket left behind by a previous process must not prevent startup.
+		stale, err := net.Listen("unix", path)
+		require.NoError(t, err)
+		stale.(*net.UnixListener).SetUnlinkOnClose(false)
+		require.NoError(t, stale.Close())
+
+		handler := NewServer(WithUnixSocket(UnixSocketConfig{
+			Path:  path,
+			Mode:  "0600",
+			Group: strconv.Itoa(os.Getgid()),
+		}))
+
+		ln, err := handler.listen()
+		require.NoError(t, err)
+
+		go func() {
+			_ = handler.serve(ln)
+		}()
+
+		t.Cleanup(func() {
+			_ = handler.server.Close()
+		})
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+		resp, err := unixClient(path).Get("http://unix/live")
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("should never remove a regular file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "httpkit.sock")
+		require.NoError(t, os.WriteFile(path, []byte("data"), 0o600))
+
+		_, err := NewServer(WithUnixSocket(UnixSocketConfig{Path: path})).listen()
+		assert.Error(t, err)
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "data", string(content))
+	})
+}
+
+func TestListeners_SystemdSocket(t *testing.T) {
+	passSocket := func(t *testing.T, names string) string {
+		t.Helper()
+
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer ln.Close()
+
+		file, err := ln.(*net.TCPListener).File()
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = file.Close() })
+
+		start := listenFDsStart
+		listenFDsStart = int(file.Fd())
+		t.Cleanup(func() { listenFDsStart = start })
+
+		t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+		t.Setenv("LISTEN_FDS", "1")
+		t.Setenv("LISTEN_FDNAMES", names)
+
+		return ln.Addr().String()
+	}
+
+	t.Run("should use the socket passed by systemd", func(t *testing.T) {
+		addr := passSocket(t, "http")
+
+		ln, err := NewServer(WithSystemdSocket(SystemdSocketConfig{Enable: true, Name: "http"})).listen()
+		require.NoError(t, err)
+		defer ln.Close()
+
+		assert.Equal(t, addr, ln.Addr().String())
+	})
+
+	t.Run("should fail for an unknown socket name", func(t *testing.T) {
+		passSocket(t, "http")
+
+		_, err := NewServer(WithSystemdSocket(SystemdSocketConfig{Enable: true, Name: "admin"})).listen()
+		assert.ErrorIs(t, err, ErrNoSystemdSocket)
+	})
+
+	t.Run("should fail without socket activation", func(t *testing.T) {
+		t.Setenv("LISTEN_PID", "")
+
+		_, err := NewServer(WithSystemdSocket(SystemdSocketConfig{Enable: true})).listen()
+		assert.ErrorIs(t, err, ErrNoSystemdSocket)
+	})
+}
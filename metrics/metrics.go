@@ -14,8 +14,8 @@ import (
 )
 
 type Config struct {
-	Port   int64 `env:"METRIC_PORT"   envDefault:"80"`
-	Enable bool  `env:"METRIC_ENABLE" envDefault:"0"`
+	Port   int64 `env:"METRIC_PORT"   envDefault:"80" yaml:"port"`
+	Enable bool  `env:"METRIC_ENABLE" envDefault:"0"  yaml:"enable"`
 }
 
 var (
@@ -24,7 +24,7 @@ var (
 			Name: "http_requests_total_by_endpoint_and_status",
 			Help: "Total number of HTTP requests by endpoint",
 		},
-		[]string{"path", "method", "status"},
+		[]string{"host", "path", "method", "status"},
 	)
 
 	requestDurationByEndpointAndStatus = prometheus.NewHistogramVec(
@@ -33,7 +33,23 @@ var (
 			Help:    "Histogram of response latency (seconds) of HTTP requests by status and endpoint.",
 			Buckets: prometheus.DefBuckets,
 		},
-		[]string{"path", "method", "status"},
+		[]string{"host", "path", "method", "status"},
+	)
+
+	requestsTotalByProtocol = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_requests_total_by_protocol",
+			Help: "Total number of HTTP requests by protocol version",
+		},
+		[]string{"protocol_version"},
+	)
+
+	shutdownDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "http_server_shutdown_duration_seconds",
+			Help:    "Histogram of the time (seconds) taken by graceful shutdowns, drain delay included.",
+			Buckets: []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60},
+		},
 	)
 )
 
@@ -41,8 +57,26 @@ func init() {
 	prometheus.MustRegister(requestsTotalByEndpointAndStatus)
 
 	prometheus.MustRegister(requestDurationByEndpointAndStatus)
+
+	prometheus.MustRegister(requestsTotalByProtocol)
+
+	prometheus.MustRegister(shutdownDuration)
+}
+
+func ObserveShutdown(duration time.Duration) {
+	shutdownDuration.Observe(duration.Seconds())
 }
 
+// Handler serves the registered metrics in the Prometheus format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// StartMetrics serves /metrics on http.DefaultServeMux and exits the process
+// when the listener fails.
+//
+// Deprecated: use httpkit.WithAdmin, which shuts down with the Handler and
+// reports errors from Run.
 func StartMetrics(port int64, enable bool, log *logger.Logger) {
 	if !enable {
 		return
@@ -77,9 +111,14 @@ func MetricsMiddleware(next http.Handler) http.Handler {
 		path := chi.RouteContext(r.Context()).RoutePattern()
 		method := r.Method
 
+		// The host pattern rather than the host keeps wildcard hosts from
+		// creating a series per tenant. It is empty for the default host.
+		hostMatch, _ := utils.HostMatchFromContext(r.Context())
+
 		duration := time.Since(start).Seconds()
 
-		requestsTotalByEndpointAndStatus.WithLabelValues(path, method, statusCode).Inc()
-		requestDurationByEndpointAndStatus.WithLabelValues(path, method, statusCode).Observe(duration)
+		requestsTotalByEndpointAndStatus.WithLabelValues(hostMatch.Pattern, path, method, statusCode).Inc()
+		requestDurationByEndpointAndStatus.WithLabelValues(hostMatch.Pattern, path, method, statusCode).Observe(duration)
+		requestsTotalByProtocol.WithLabelValues(utils.ProtocolVersion(r)).Inc()
 	})
 }
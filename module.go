@@ -0,0 +1,155 @@
+package httpkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync/atomic"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/philippe-berto/httpkit/health"
+)
+
+type (
+	// Module is a feature mounted by the Handler with its routes, health
+	// checks and lifecycle.
+	Module interface {
+		// Name identifies the module in errors, hooks and /status, where its
+		// checks are reported as "name/check".
+		Name() string
+		// Mount places the module like a SubDomain.
+		Mount() ModuleMount
+		Routes(router chi.Router)
+		// HealthCheckers are registered as critical checks.
+		HealthCheckers() []health.Checker
+		// Start runs as a start hook of Run, and Shutdown as a shutdown hook,
+		// only once Start succeeded.
+		Start(ctx context.Context) error
+		Shutdown(ctx context.Context) error
+	}
+
+	// ModuleMount serves a Module under Path, for Host only when set. See
+	// SubDomain for the host patterns.
+	ModuleMount struct {
+		Path string
+		Host string
+	}
+)
+
+func (m ModuleMount) subdomain(router chi.Router) *SubDomain {
+	return &SubDomain{Domain: m.Path, Host: m.Host, Router: router}
+}
+
+// moduleHooks runs the Shutdown of module only after its Start succeeded, so
+// a failed start only stops the modules started before it.
+func moduleHooks(module Module) (start, stop Hook) {
+	var started atomic.Bool
+
+	start = Hook{Name: "module " + module.Name(), Run: func(ctx context.Context) error {
+		if err := module.Start(ctx); err != nil {
+			return err
+		}
+
+		started.Store(true)
+
+		return nil
+	}}
+
+	stop = Hook{Name: "module " + module.Name(), Run: func(ctx context.Context) error {
+		if !started.Load() {
+			return nil
+		}
+
+		return module.Shutdown(ctx)
+	}}
+
+	return start, stop
+}
+
+// mountModules turns modules into SubDomains, registering their health
+// checks and hooks, then returns every SubDomain that can be mounted along
+// with the conflicts found.
+func (h *Handler) mountModules(subdomains []*SubDomain, modules []Module) ([]*SubDomain, error) {
+	var (
+		errs   []error
+		names  = map[*SubDomain]string{}
+		all    = slices.Clone(subdomains)
+		byName = map[string]bool{}
+	)
+
+	for _, subdomain := range subdomains {
+		names[subdomain] = fmt.Sprintf("subdomain %q", subdomain.Host+subdomain.Domain)
+	}
+
+	for _, module := range modules {
+		name := module.Name()
+		if byName[name] {
+			errs = append(errs, fmt.Errorf("%w: module %s is registered twice", ErrInvalidConfig, name))
+
+			continue
+		}
+
+		byName[name] = true
+
+		router := chi.NewRouter()
+		module.Routes(router)
+
+		subdomain := module.Mount().subdomain(router)
+		names[subdomain] = "module " + name
+		all = append(all, subdomain)
+
+		for _, checker := range module.HealthCheckers() {
+			h.RegisterHealthCheck(health.Named(name+"/"+checker.Name(), checker))
+		}
+
+		start, stop := moduleHooks(module)
+		h.OnStart(start)
+		h.OnShutdown(stop)
+	}
+
+	mounted := make([]*SubDomain, 0, len(all))
+	claimed := map[string]*SubDomain{}
+
+	for _, subdomain := range all {
+		if err := validateMount(subdomain); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %s %w", ErrInvalidConfig, names[subdomain], err))
+
+			continue
+		}
+
+		key := normalizeHost(subdomain.Host) + subdomain.prefix()
+		if other, ok := claimed[key]; ok {
+			errs = append(errs, fmt.Errorf("%w: %s conflicts with %s", ErrInvalidConfig, names[subdomain], names[other]))
+
+			continue
+		}
+
+		claimed[key] = subdomain
+		mounted = append(mounted, subdomain)
+	}
+
+	return mounted, errors.Join(errs...)
+}
+
+func validateMount(subdomain *SubDomain) error {
+	if subdomain.Domain != "" && subdomain.Domain[0] != '/' {
+		return fmt.Errorf("path %q must start with /", subdomain.Domain)
+	}
+
+	if subdomain.Host != "" {
+		return nil
+	}
+
+	// Domain "/" mounts at the root, behind the probes.
+	if subdomain.Domain == "" {
+		return errors.New("needs a path or a host")
+	}
+
+	if slices.Contains(probePaths, subdomain.prefix()) {
+		return fmt.Errorf("path %q is reserved for probes", subdomain.Domain)
+	}
+
+	return nil
+}
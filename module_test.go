@@ -0,0 +1,211 @@
+package httpkit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/philippe-berto/httpkit/health"
+)
+
+type testModule struct {
+	name     string
+	mount    ModuleMount
+	checkErr error
+	startErr error
+	events   *[]string
+}
+
+func (m testModule) Name() string       { return m.name }
+func (m testModule) Mount() ModuleMount { return m.mount }
+
+func (m testModule) Routes(router chi.Router) {
+	router.Get("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(m.name))
+	})
+}
+
+func (m testModule) HealthCheckers() []health.Checker {
+	return []health.Checker{health.CheckerFunc("db", func(context.Context) error { return m.checkErr })}
+}
+
+func (m testModule) Start(context.Context) error {
+	*m.events = append(*m.events, "start "+m.name)
+
+	return m.startErr
+}
+
+func (m testModule) Shutdown(context.Context) error {
+	*m.events = append(*m.events, "shutdown "+m.name)
+
+	return nil
+}
+
+func TestModule_Mount(t *testing.T) {
+	var events []string
+
+	handler := NewServer(WithModules(
+		testModule{name: "billing", mount: ModuleMount{Path: "/billing"}, events: &events},
+		testModule{name: "tenants", mount: ModuleMount{Host: "*.tenant.example.com"}, events: &events},
+	))
+
+	t.Run("should serve the module routes under its path", func(t *testing.T) {
+		assert.Equal(t, "billing", hostRequest(handler, "example.com", "/billing/whoami").Body.String())
+	})
+
+	t.Run("should serve the module routes for its host", func(t *testing.T) {
+		assert.Equal(t, "tenants", hostRequest(handler, "acme.tenant.example.com", "/whoami").Body.String())
+	})
+
+	t.Run("should report module checks in /status", func(t *testing.T) {
+		w := hostRequest(handler, "example.com", "/status")
+
+		var report struct {
+			Checks []struct {
+				Name string `json:"name"`
+			} `json:"checks"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+
+		require.Len(t, report.Checks, 2)
+		assert.Equal(t, "billing/db", report.Checks[0].Name)
+		assert.Equal(t, "tenants/db", report.Checks[1].Name)
+	})
+
+	t.Run("should start the modules in order and shut them down in reverse", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		handler := NewServer(WithPort(0), WithModules(
+			testModule{name: "db", mount: ModuleMount{Path: "/db"}, events: &events},
+			testModule{name: "billing", mount: ModuleMount{Path: "/billing"}, events: &events},
+		))
+
+		done := runForTest(handler, ctx)
+
+		require.Eventually(t, func() bool { return handler.Addr() != nil }, time.Second, 5*time.Millisecond)
+		cancel()
+		require.NoError(t, <-done)
+
+		assert.Equal(t, []string{"start db", "start billing", "shutdown billing", "shutdown db"}, events)
+	})
+
+	t.Run("should only shut down the modules started when one fails to start", func(t *testing.T) {
+		var events []string
+
+		handler := NewServer(WithPort(0), WithModules(
+			testModule{name: "db", mount: ModuleMount{Path: "/db"}, events: &events},
+			testModule{name: "cache", mount: ModuleMount{Path: "/cache"}, events: &events},
+			testModule{name: "billing", mount: ModuleMount{Path: "/billing"}, startErr: errors.New("billing unavailable"), events: &events},
+			testModule{name: "search", mount: ModuleMount{Path: "/search"}, events: &events},
+		))
+
+		err := handler.Run(context.Background())
+
+		assert.ErrorContains(t, err, "billing unavailable")
+		assert.Equal(t, []string{"start db", "start cache", "start billing", "shutdown cache", "shutdown db"}, events)
+	})
+}
+
+func TestModule_Health(t *testing.T) {
+	t.Run("should fail readiness when a module check fails", func(t *testing.T) {
+		var events []string
+
+		handler := NewServer(WithModules(testModule{
+			name:     "billing",
+			mount:    ModuleMount{Path: "/billing"},
+			checkErr: errors.New("billing db unreachable"),
+			events:   &events,
+		}))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	})
+}
+
+func TestModule_Conflicts(t *testing.T) {
+	var events []string
+
+	module := func(name string, mount ModuleMount) Module {
+		return testModule{name: name, mount: mount, events: &events}
+	}
+
+	t.Run("should report conflicting paths at startup", func(t *testing.T) {
+		handler := NewServer(
+			WithSubDomains(&SubDomain{Domain: "/billing", Router: chi.NewRouter()}),
+			WithModules(module("billing", ModuleMount{Path: "/billing/"})),
+		)
+
+		err := handler.Run(context.Background())
+
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+		assert.ErrorContains(t, err, `module billing conflicts with subdomain "/billing"`)
+		assert.Empty(t, events)
+	})
+
+	t.Run("should report conflicting hosts", func(t *testing.T) {
+		handler := NewServer(WithModules(
+			module("a", ModuleMount{Host: "api.example.com"}),
+			module("b", ModuleMount{Host: "API.example.com"}),
+			module("c", ModuleMount{Host: "api.example.com", Path: "/c"}),
+		))
+
+		err := handler.Start()
+
+		assert.ErrorContains(t, err, "module b conflicts with module a")
+		assert.NotContains(t, err.Error(), "module c")
+	})
+
+	t.Run("should reject invalid mounts", func(t *testing.T) {
+		handler := NewServer(WithModules(
+			module("probe", ModuleMount{Path: "/ready"}),
+			module("relative", ModuleMount{Path: "api"}),
+			module("nowhere", ModuleMount{}),
+			module("nowhere", ModuleMount{Path: "/twice"}),
+		))
+
+		err := handler.Start()
+		assert.Equal(t, handler.Err(), err)
+
+		assert.ErrorContains(t, err, "module probe path \"/ready\" is reserved for probes")
+		assert.ErrorContains(t, err, "module relative path \"api\" must start with /")
+		assert.ErrorContains(t, err, "module nowhere needs a path or a host")
+		assert.ErrorContains(t, err, "module nowhere is registered twice")
+	})
+}
+
+func TestModule_RootMount(t *testing.T) {
+	t.Run("should serve a SubDomain mounted at the root behind the probes", func(t *testing.T) {
+		sub := chi.NewRouter()
+		sub.Get("/users", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		})
+
+		handler := New(0, false, false, false, "", &SubDomain{Domain: "/", Router: sub})
+		require.NoError(t, handler.Err())
+
+		assert.Equal(t, http.StatusTeapot, hostRequest(handler, "example.com", "/users").Code)
+		assert.Equal(t, http.StatusOK, hostRequest(handler, "example.com", "/live").Code)
+	})
+
+	t.Run("should fail NewFromConfig on invalid mounts", func(t *testing.T) {
+		var events []string
+
+		handler, err := NewFromConfig(Config{Port: 8080}, WithModules(
+			testModule{name: "a", mount: ModuleMount{Path: "/api"}, events: &events},
+			testModule{name: "b", mount: ModuleMount{Path: "/api"}, events: &events},
+		))
+
+		assert.Nil(t, handler)
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+		assert.ErrorContains(t, err, "module b conflicts with module a")
+	})
+}
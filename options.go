@@ -0,0 +1,273 @@
+package httpkit
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/philippe-berto/httpkit/tracing"
+	"github.com/philippe-berto/logger"
+)
+
+const (
+	defaultPort            = 8080
+	defaultShutdownTimeout = 10 * time.Second
+)
+
+type (
+	Option func(*options)
+
+	options struct {
+		port             int
+		tracing          bool
+		metrics          bool
+		cors             bool
+		corsAllowOrigins string
+		subdomains       []*SubDomain
+		middlewares      []func(http.Handler) http.Handler
+		timeouts         Timeouts
+		limits           Limits
+		shutdownTimeout  time.Duration
+		tls              *TLSConfig
+		http2            *HTTP2Config
+		log              *logger.Logger
+		admin            *AdminConfig
+		components       []Component
+
+		drainDelay        time.Duration
+		shutdownObservers []func(ShutdownPhase)
+		manualStartup     bool
+		listen            listenConfig
+		restart           *RestartConfig
+		proxyProtocol     *ProxyProtocolConfig
+		trustedProxies    []string
+		modules           []Module
+		propagators       []string
+		tracingOptions    []tracing.Option
+	}
+)
+
+func defaultOptions() options {
+	return options{
+		port:            defaultPort,
+		shutdownTimeout: defaultShutdownTimeout,
+		timeouts:        DefaultTimeouts(),
+		limits:          DefaultLimits(),
+	}
+}
+
+// WithPort sets the TCP port the server listens on. Defaults to 8080.
+func WithPort(port int) Option {
+	return func(o *options) {
+		o.port = port
+	}
+}
+
+// WithTracing enables the OpenTelemetry tracing middleware, configured by
+// opts. It continues the traces extracted by the propagators set with
+// WithPropagators, and records spans with the global TracerProvider unless
+// opts set one. A tracing.SamplingPolicy needs a provider built by
+// tracing.Setup, or NewServer reports it, see Handler.Err.
+func WithTracing(opts ...tracing.Option) Option {
+	return func(o *options) {
+		o.tracing = true
+		o.tracingOptions = append(o.tracingOptions, opts...)
+	}
+}
+
+// WithMetrics enables the Prometheus metrics middleware.
+func WithMetrics() Option {
+	return func(o *options) {
+		o.metrics = true
+	}
+}
+
+// WithCORS enables the CORS middleware answering with the given allowed origins.
+func WithCORS(allowOrigins string) Option {
+	return func(o *options) {
+		o.cors = true
+		o.corsAllowOrigins = allowOrigins
+	}
+}
+
+// WithSubDomains mounts the given routers under their Domain prefix.
+func WithSubDomains(subdomains ...*SubDomain) Option {
+	return func(o *options) {
+		o.subdomains = append(o.subdomains, subdomains...)
+	}
+}
+
+// WithMiddleware appends middlewares to the global stack. They run after the
+// built-in ones, in the order they are given.
+func WithMiddleware(middlewares ...func(http.Handler) http.Handler) Option {
+	return func(o *options) {
+		o.middlewares = append(o.middlewares, middlewares...)
+	}
+}
+
+// WithTimeouts sets the read, write and idle timeouts of the underlying
+// http.Server. Defaults to DefaultTimeouts; zero values disable a timeout.
+func WithTimeouts(timeouts Timeouts) Option {
+	return func(o *options) {
+		o.timeouts = timeouts
+	}
+}
+
+// WithShutdownTimeout sets how long a graceful shutdown waits for in-flight
+// requests. Defaults to 10 seconds.
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.shutdownTimeout = timeout
+	}
+}
+
+// WithTLS serves HTTPS using the given certificate, which is reloaded from disk
+// when it changes, checked every ReloadInterval, or when the process receives
+// SIGHUP.
+func WithTLS(cfg TLSConfig) Option {
+	return func(o *options) {
+		if cfg.ReloadInterval == 0 {
+			cfg.ReloadInterval = defaultTLSReloadInterval
+		}
+
+		o.tls = &cfg
+	}
+}
+
+// WithLogger sets the logger used to report errors from background tasks.
+func WithLogger(log *logger.Logger) Option {
+	return func(o *options) {
+		o.log = log
+	}
+}
+
+// WithHTTP2 tunes the HTTP/2 server and optionally enables h2c on cleartext
+// listeners. Without it, HTTP/2 is only negotiated over TLS with Go defaults.
+func WithHTTP2(cfg HTTP2Config) Option {
+	return func(o *options) {
+		o.http2 = &cfg
+	}
+}
+
+// WithLimits sets the header size and global body size limits. Defaults to
+// DefaultLimits.
+func WithLimits(limits Limits) Option {
+	return func(o *options) {
+		o.limits = limits
+	}
+}
+
+// WithAdmin serves /metrics, the probes, build info and optionally pprof and
+// expvar on a dedicated port while Run is running.
+func WithAdmin(cfg AdminConfig) Option {
+	return func(o *options) {
+		o.admin = &cfg
+	}
+}
+
+// WithMetricsServer serves the admin endpoints, /metrics included, on port
+// while Run is running.
+func WithMetricsServer(port int64) Option {
+	return WithAdmin(AdminConfig{Port: port})
+}
+
+// WithComponents registers background components started and stopped by Run.
+func WithComponents(components ...Component) Option {
+	return func(o *options) {
+		o.components = append(o.components, components...)
+	}
+}
+
+// WithDrainDelay keeps serving traffic for delay after shutdown starts while
+// /ready answers 503, giving load balancers time to stop routing requests.
+func WithDrainDelay(delay time.Duration) Option {
+	return func(o *options) {
+		o.drainDelay = delay
+	}
+}
+
+// WithShutdownObserver calls observe when shutdown enters each ShutdownPhase.
+func WithShutdownObserver(observe func(ShutdownPhase)) Option {
+	return func(o *options) {
+		o.shutdownObservers = append(o.shutdownObservers, observe)
+	}
+}
+
+// WithManualStartup keeps /startup and /ready answering 503 until
+// Handler.MarkStarted is called, for services that warm up after construction.
+func WithManualStartup() Option {
+	return func(o *options) {
+		o.manualStartup = true
+	}
+}
+
+// WithListener serves on ln instead of opening a TCP listener on the port.
+func WithListener(ln net.Listener) Option {
+	return func(o *options) {
+		o.listen = listenConfig{listener: ln}
+	}
+}
+
+// WithUnixSocket serves on a Unix domain socket instead of the TCP port.
+func WithUnixSocket(cfg UnixSocketConfig) Option {
+	return func(o *options) {
+		o.listen = listenConfig{unix: &cfg}
+	}
+}
+
+// WithSystemdSocket serves on a socket passed by systemd socket activation
+// through LISTEN_FDS instead of the TCP port.
+func WithSystemdSocket(cfg SystemdSocketConfig) Option {
+	return func(o *options) {
+		o.listen = listenConfig{systemd: &cfg}
+	}
+}
+
+// WithGracefulRestart hands the listeners off to a new process of the same
+// executable on SIGUSR2 and shuts down once it serves. Only Run supports it,
+// on Unix.
+func WithGracefulRestart(cfg RestartConfig) Option {
+	return func(o *options) {
+		if cfg.ReadyTimeout <= 0 {
+			cfg.ReadyTimeout = defaultRestartReadyTimeout
+		}
+
+		o.restart = &cfg
+	}
+}
+
+// WithProxyProtocol reads the PROXY protocol header sent by trusted load
+// balancers, so r.RemoteAddr holds the real client address.
+func WithProxyProtocol(cfg ProxyProtocolConfig) Option {
+	return func(o *options) {
+		o.proxyProtocol = &cfg
+	}
+}
+
+// WithTrustedProxies trusts the forwarding headers (Forwarded, X-Forwarded-For,
+// X-Real-IP and X-Forwarded-Proto/Host) set by proxies within cidrs, given as
+// CIDRs or single addresses. Headers from any other peer are ignored by
+// utils.ClientIP, utils.Scheme and utils.Host.
+func WithTrustedProxies(cidrs ...string) Option {
+	return func(o *options) {
+		o.trustedProxies = append(o.trustedProxies, cidrs...)
+	}
+}
+
+// WithModules mounts modules and registers their health checks and hooks.
+// Invalid or conflicting mounts are not mounted and are reported by
+// Handler.Err, NewFromConfig, Run and Start.
+func WithModules(modules ...Module) Option {
+	return func(o *options) {
+		o.modules = append(o.modules, modules...)
+	}
+}
+
+// WithPropagators sets the formats of the trace context extracted by the
+// tracing middleware, see tracing.NewPropagator. Defaults to
+// tracing.DefaultPropagators(). The global propagator is left untouched.
+func WithPropagators(names ...string) Option {
+	return func(o *options) {
+		o.propagators = names
+	}
+}
@@ -0,0 +1,113 @@
+package httpkit
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/philippe-berto/httpkit/health"
+	"github.com/philippe-berto/httpkit/utils"
+)
+
+// ReadinessGate holds /ready at 503 while it is closed. Gates start closed.
+type ReadinessGate struct {
+	name string
+	open atomic.Bool
+}
+
+func (g *ReadinessGate) Name() string {
+	return g.name
+}
+
+func (g *ReadinessGate) Open() {
+	g.open.Store(true)
+}
+
+func (g *ReadinessGate) Close() {
+	g.open.Store(false)
+}
+
+func (g *ReadinessGate) IsOpen() bool {
+	return g.open.Load()
+}
+
+// AddReadinessGate registers a closed gate, so the handler is not ready until
+// the returned gate is opened.
+func (h *Handler) AddReadinessGate(name string) *ReadinessGate {
+	gate := &ReadinessGate{name: name}
+
+	h.gatesMu.Lock()
+	h.gates = append(h.gates, gate)
+	h.gatesMu.Unlock()
+
+	return gate
+}
+
+// MarkStarted completes startup when the handler was built WithManualStartup.
+func (h *Handler) MarkStarted() {
+	h.started.Store(true)
+}
+
+// RegisterHealthCheck adds a dependency check reported by /status. Critical
+// checks, the default, also fail /ready.
+func (h *Handler) RegisterHealthCheck(checker health.Checker, opts ...health.CheckOption) {
+	h.health.Register(checker, opts...)
+}
+
+func (h *Handler) closedGates() []string {
+	h.gatesMu.RLock()
+	defer h.gatesMu.RUnlock()
+
+	var closed []string
+
+	for _, gate := range h.gates {
+		if !gate.IsOpen() {
+			closed = append(closed, gate.name)
+		}
+	}
+
+	return closed
+}
+
+// getStartup only tells whether the service finished starting, so the
+// orchestrator can hold liveness and readiness probes until then.
+func (h *Handler) getStartup(w http.ResponseWriter, r *http.Request) {
+	if !h.started.Load() {
+		_ = utils.WriteBody(w, http.StatusServiceUnavailable, map[string]string{"message": "starting"})
+
+		return
+	}
+
+	GetStatus(w, r)
+}
+
+func (h *Handler) getReady(w http.ResponseWriter, r *http.Request) {
+	if !h.started.Load() {
+		_ = utils.WriteBody(w, http.StatusServiceUnavailable, map[string]string{"message": "starting"})
+
+		return
+	}
+
+	if h.draining.Load() {
+		_ = utils.WriteBody(w, http.StatusServiceUnavailable, map[string]string{"message": "draining"})
+
+		return
+	}
+
+	if closed := h.closedGates(); len(closed) > 0 {
+		_ = utils.WriteBody(w, http.StatusServiceUnavailable, map[string]interface{}{"message": "not ready", "gates": closed})
+
+		return
+	}
+
+	if h.health.Run(r.Context()).Status == health.StatusDown {
+		_ = utils.WriteBody(w, http.StatusServiceUnavailable, map[string]string{"message": "unhealthy"})
+
+		return
+	}
+
+	GetStatus(w, r)
+}
+
+func (h *Handler) getStatus(w http.ResponseWriter, r *http.Request) {
+	h.health.Handler().ServeHTTP(w, r)
+}
@@ -0,0 +1,58 @@
+package httpkit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/philippe-berto/httpkit/utils"
+)
+
+func TestProbes_Startup(t *testing.T) {
+	t.Run("should hold startup and readiness until marked started", func(t *testing.T) {
+		handler := NewServer(WithManualStartup())
+
+		assert.Equal(t, http.StatusOK, serveRecorded(t, handler, "/live").Code)
+		assert.Equal(t, http.StatusServiceUnavailable, serveRecorded(t, handler, "/startup").Code)
+		assert.Equal(t, http.StatusServiceUnavailable, serveRecorded(t, handler, "/ready").Code)
+
+		handler.MarkStarted()
+
+		assert.Equal(t, http.StatusOK, serveRecorded(t, handler, "/startup").Code)
+		assert.Equal(t, http.StatusOK, serveRecorded(t, handler, "/ready").Code)
+	})
+
+	t.Run("should be started by default", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, serveRecorded(t, NewServer(), "/startup").Code)
+	})
+}
+
+func TestProbes_ReadinessGates(t *testing.T) {
+	t.Run("should not be ready while a gate is closed", func(t *testing.T) {
+		handler := NewServer()
+		warmup := handler.AddReadinessGate("cache-warmup")
+		consumer := handler.AddReadinessGate("consumer")
+		consumer.Open()
+
+		responseWriter := serveRecorded(t, handler, "/ready")
+		assert.Equal(t, http.StatusServiceUnavailable, responseWriter.Code)
+		assert.Contains(t, responseWriter.Body.String(), `"gates":["cache-warmup"]`)
+		assert.Equal(t, http.StatusOK, serveRecorded(t, handler, "/live").Code)
+
+		warmup.Open()
+		assert.Equal(t, http.StatusOK, serveRecorded(t, handler, "/ready").Code)
+
+		consumer.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, serveRecorded(t, handler, "/ready").Code)
+	})
+}
+
+func TestProbes_Instrumentation(t *testing.T) {
+	t.Run("should exclude probes from metrics and tracing", func(t *testing.T) {
+		for _, path := range []string{"/live", "/ready", "/startup", "/status"} {
+			assert.True(t, utils.CheckInValidPath(httptest.NewRequest(http.MethodGet, path, nil)), path)
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package httpkit
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/pires/go-proxyproto"
+
+	"github.com/philippe-berto/httpkit/utils"
+)
+
+// ProxyProtocolConfig accepts HAProxy PROXY protocol v1 and v2 headers from
+// TCP load balancers, so r.RemoteAddr holds the real client address.
+type ProxyProtocolConfig struct {
+	Enable bool `env:"HTTP_PROXY_PROTOCOL" envDefault:"false" yaml:"enable"`
+	// TrustedCIDRs lists the load balancers allowed to send a PROXY header,
+	// as CIDRs or single addresses. Other peers sending one are rejected.
+	// Peers without an IP address, such as on a Unix socket, are trusted.
+	TrustedCIDRs []string `env:"HTTP_PROXY_PROTOCOL_TRUSTED_CIDRS" envSeparator:"," yaml:"trusted_cidrs"`
+	// Required rejects trusted peers connecting without a PROXY header.
+	Required bool `env:"HTTP_PROXY_PROTOCOL_REQUIRED" envDefault:"false" yaml:"required"`
+}
+
+func (c ProxyProtocolConfig) validate() error {
+	if len(c.TrustedCIDRs) == 0 {
+		return fmt.Errorf("%w: proxy_protocol.trusted_cidrs must not be empty", ErrInvalidConfig)
+	}
+
+	_, err := parsePrefixes(c.TrustedCIDRs)
+
+	return err
+}
+
+// listener wraps ln to read the PROXY header of trusted peers within
+// headerTimeout.
+func (c ProxyProtocolConfig) listener(ln net.Listener, headerTimeout time.Duration) (net.Listener, error) {
+	trusted, err := parsePrefixes(c.TrustedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+
+	trustedPolicy := proxyproto.USE
+	if c.Required {
+		trustedPolicy = proxyproto.REQUIRE
+	}
+
+	return &proxyproto.Listener{
+		Listener:          ln,
+		ReadHeaderTimeout: headerTimeout,
+		Policy: func(upstream net.Addr) (proxyproto.Policy, error) {
+			tcpAddr, ok := upstream.(*net.TCPAddr)
+			if !ok {
+				return trustedPolicy, nil
+			}
+
+			addr := tcpAddr.AddrPort().Addr().Unmap()
+
+			for _, prefix := range trusted {
+				if prefix.Contains(addr) {
+					return trustedPolicy, nil
+				}
+			}
+
+			return proxyproto.REJECT, nil
+		},
+	}, nil
+}
+
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes, err := utils.ParseCIDRs(cidrs)
+	if err != nil {
+		return nil, fmt.Errorf("%w: proxy_protocol.trusted_cidrs: %w", ErrInvalidConfig, err)
+	}
+
+	return prefixes, nil
+}
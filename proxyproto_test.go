@@ -0,0 +1,113 @@
+package httpkit
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/pires/go-proxyproto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func proxyProtocolServer(t *testing.T, cfg ProxyProtocolConfig) string {
+	t.Helper()
+
+	handler := NewServer(WithProxyProtocol(cfg))
+	handler.Router.Get("/addr", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.RemoteAddr))
+	})
+
+	return serveForTest(t, handler)
+}
+
+// requestWithHeader sends a request preceded by header, when set, and returns
+// the response status and body.
+func requestWithHeader(t *testing.T, addr string, header *proxyproto.Header) (int, string) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	if header != nil {
+		_, err = header.WriteTo(conn)
+		require.NoError(t, err)
+	}
+
+	_, err = conn.Write([]byte("GET /addr HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+	require.NoError(t, err)
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp.StatusCode, string(body)
+}
+
+func proxyHeader(version byte) *proxyproto.Header {
+	return proxyproto.HeaderProxyFromAddrs(version,
+		&net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 51000},
+		&net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 443},
+	)
+}
+
+func TestProxyProtocol_Listener(t *testing.T) {
+	t.Run("should expose the client address sent by a trusted proxy", func(t *testing.T) {
+		addr := proxyProtocolServer(t, ProxyProtocolConfig{Enable: true, TrustedCIDRs: []string{"127.0.0.0/8"}})
+
+		for _, version := range []byte{1, 2} {
+			status, body := requestWithHeader(t, addr, proxyHeader(version))
+
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, "203.0.113.7:51000", body)
+		}
+	})
+
+	t.Run("should serve trusted peers without a header unless required", func(t *testing.T) {
+		addr := proxyProtocolServer(t, ProxyProtocolConfig{Enable: true, TrustedCIDRs: []string{"127.0.0.1"}})
+
+		status, body := requestWithHeader(t, addr, nil)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Contains(t, body, "127.0.0.1:")
+
+		addr = proxyProtocolServer(t, ProxyProtocolConfig{Enable: true, TrustedCIDRs: []string{"127.0.0.1"}, Required: true})
+
+		status, _ = requestWithHeader(t, addr, nil)
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+
+	t.Run("should reject headers from untrusted peers", func(t *testing.T) {
+		addr := proxyProtocolServer(t, ProxyProtocolConfig{Enable: true, TrustedCIDRs: []string{"10.0.0.0/8"}})
+
+		status, _ := requestWithHeader(t, addr, proxyHeader(1))
+		assert.Equal(t, http.StatusBadRequest, status)
+
+		status, body := requestWithHeader(t, addr, nil)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Contains(t, body, "127.0.0.1:")
+	})
+}
+
+func TestProxyProtocol_Validate(t *testing.T) {
+	t.Run("should require trusted CIDRs", func(t *testing.T) {
+		assert.ErrorIs(t, ProxyProtocolConfig{Enable: true}.validate(), ErrInvalidConfig)
+	})
+
+	t.Run("should reject invalid CIDRs", func(t *testing.T) {
+		err := ProxyProtocolConfig{Enable: true, TrustedCIDRs: []string{"10.0.0.0/8", "10.0.0.0/33"}}.validate()
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+
+		err = ProxyProtocolConfig{Enable: true, TrustedCIDRs: []string{"proxy.internal"}}.validate()
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+	})
+
+	t.Run("should accept CIDRs and single addresses", func(t *testing.T) {
+		assert.NoError(t, ProxyProtocolConfig{Enable: true, TrustedCIDRs: []string{"10.0.0.0/8", "::1"}}.validate())
+	})
+}
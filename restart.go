@@ -0,0 +1,58 @@
+package httpkit
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/philippe-berto/logger"
+)
+
+const (
+	defaultRestartReadyTimeout = 30 * time.Second
+
+	httpListenerName  = "http"
+	adminListenerName = "admin"
+
+	// restartFDsEnv names the listeners handed to a new process, in the order
+	// of their file descriptors starting at 3. restartReadyEnv holds the file
+	// descriptor the new process writes to once it serves.
+	restartFDsEnv   = "HTTPKIT_LISTEN_FDNAMES"
+	restartReadyEnv = "HTTPKIT_READY_FD"
+)
+
+var ErrRestartFailed = errors.New("graceful restart failed")
+
+// RestartConfig enables zero-downtime binary upgrades on Unix: on SIGUSR2 the
+// handler starts its executable again, passing it the listening sockets, and
+// shuts down gracefully once the new process serves.
+type RestartConfig struct {
+	Enable bool `env:"HTTP_GRACEFUL_RESTART" envDefault:"false" yaml:"enable"`
+	// ReadyTimeout bounds the wait for the new process. When it expires the
+	// new process is killed and the current one keeps serving.
+	ReadyTimeout time.Duration `env:"HTTP_RESTART_READY_TIMEOUT" envDefault:"30s" yaml:"ready_timeout"`
+}
+
+// restartSignals returns the channel receiving restart requests, which is nil
+// when graceful restarts are disabled.
+func (h *Handler) restartSignals() (<-chan os.Signal, func()) {
+	if h.restart == nil {
+		return nil, func() {}
+	}
+
+	return notifyRestart()
+}
+
+func (h *Handler) logRestart(err error) {
+	if h.log == nil {
+		return
+	}
+
+	if err != nil {
+		h.log.WithFields(logger.Fields{"error": err}).Error("Graceful restart failed, still serving")
+
+		return
+	}
+
+	h.log.Info("Handed listeners off to the new process, shutting down")
+}
@@ -0,0 +1,23 @@
+//go:build !unix
+
+package httpkit
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+func notifyRestart() (<-chan os.Signal, func()) {
+	return nil, func() {}
+}
+
+func (h *Handler) handoff(net.Listener, net.Listener) error {
+	return fmt.Errorf("%w: not supported on this platform", ErrRestartFailed)
+}
+
+func inheritedListener(string) net.Listener {
+	return nil
+}
+
+func signalRestartReady() {}
@@ -0,0 +1,230 @@
+//go:build unix
+
+package httpkit
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	// executable and restartArgs select the command started on restart.
+	executable  = os.Executable
+	restartArgs = func() []string { return os.Args[1:] }
+
+	inherited struct {
+		once      sync.Once
+		mu        sync.Mutex
+		listeners map[string]net.Listener
+		ready     *os.File
+	}
+)
+
+func notifyRestart() (<-chan os.Signal, func()) {
+	restarts := make(chan os.Signal, 1)
+	signal.Notify(restarts, syscall.SIGUSR2)
+
+	return restarts, func() { signal.Stop(restarts) }
+}
+
+// handoff starts a new process of the same executable inheriting ln and
+// adminLn, and waits for it to report that it serves.
+func (h *Handler) handoff(ln, adminLn net.Listener) error {
+	names := []string{httpListenerName}
+	listeners := []net.Listener{ln}
+
+	if adminLn != nil {
+		names = append(names, adminListenerName)
+		listeners = append(listeners, adminLn)
+	}
+
+	var files []*os.File
+
+	defer func() {
+		for _, file := range files {
+			_ = file.Close()
+		}
+	}()
+
+	for _, l := range listeners {
+		file, err := listenerFile(l)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrRestartFailed, err)
+		}
+
+		files = append(files, file)
+	}
+
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrRestartFailed, err)
+	}
+	defer readyR.Close()
+
+	files = append(files, readyW)
+
+	path, err := executable()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrRestartFailed, err)
+	}
+
+	cmd := exec.Command(path, restartArgs()...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(restartEnv(os.Environ()),
+		restartFDsEnv+"="+strings.Join(names, ":"),
+		restartReadyEnv+"="+strconv.Itoa(listenFDsStart+len(names)),
+	)
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("%w: %w", ErrRestartFailed, err)
+	}
+
+	// Close the write end here so the read below fails as soon as the new
+	// process exits without reporting.
+	_ = readyW.Close()
+	files = files[:len(files)-1]
+
+	_ = readyR.SetReadDeadline(time.Now().Add(h.restart.ReadyTimeout))
+
+	if _, err := readyR.Read(make([]byte, 1)); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+
+		return fmt.Errorf("%w: new process did not become ready: %w", ErrRestartFailed, err)
+	}
+
+	// The socket file now belongs to the new process.
+	if unixLn, ok := ln.(*net.UnixListener); ok {
+		unixLn.SetUnlinkOnClose(false)
+	}
+
+	return cmd.Process.Release()
+}
+
+// listenerFile duplicates the descriptor of ln. Unlike the File method of
+// net listeners, it leaves the shared descriptor non-blocking, so the Accept
+// loop of the current process can still be interrupted by Close.
+func listenerFile(ln net.Listener) (*os.File, error) {
+	conn, ok := ln.(syscall.Conn)
+	if !ok {
+		return nil, fmt.Errorf("listener %T cannot be passed to a new process", ln)
+	}
+
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		fd     int
+		dupErr error
+	)
+
+	err = raw.Control(func(sysfd uintptr) {
+		syscall.ForkLock.RLock()
+		defer syscall.ForkLock.RUnlock()
+
+		if fd, dupErr = syscall.Dup(int(sysfd)); dupErr == nil {
+			syscall.CloseOnExec(fd)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if dupErr != nil {
+		return nil, dupErr
+	}
+
+	return os.NewFile(uintptr(fd), "httpkit:"+ln.Addr().String()), nil
+}
+
+// restartEnv drops the handoff variables of a previous restart.
+func restartEnv(environ []string) []string {
+	env := make([]string, 0, len(environ))
+
+	for _, kv := range environ {
+		if strings.HasPrefix(kv, restartFDsEnv+"=") || strings.HasPrefix(kv, restartReadyEnv+"=") {
+			continue
+		}
+
+		env = append(env, kv)
+	}
+
+	return env
+}
+
+func loadInherited() {
+	inherited.once.Do(func() {
+		inherited.listeners = map[string]net.Listener{}
+
+		names := os.Getenv(restartFDsEnv)
+		if names == "" {
+			return
+		}
+
+		for i, name := range strings.Split(names, ":") {
+			fd := listenFDsStart + i
+			syscall.CloseOnExec(fd)
+
+			file := os.NewFile(uintptr(fd), "httpkit:"+name)
+
+			ln, err := net.FileListener(file)
+			_ = file.Close()
+
+			if err == nil {
+				inherited.listeners[name] = ln
+			}
+		}
+
+		if fd, err := strconv.Atoi(os.Getenv(restartReadyEnv)); err == nil {
+			syscall.CloseOnExec(fd)
+			inherited.ready = os.NewFile(uintptr(fd), "httpkit:ready")
+		}
+
+		_ = os.Unsetenv(restartFDsEnv)
+		_ = os.Unsetenv(restartReadyEnv)
+	})
+}
+
+// inheritedListener returns the listener named name passed by the process
+// that restarted this one, or nil. Each listener is returned only once.
+func inheritedListener(name string) net.Listener {
+	loadInherited()
+
+	inherited.mu.Lock()
+	defer inherited.mu.Unlock()
+
+	ln := inherited.listeners[name]
+	delete(inherited.listeners, name)
+
+	return ln
+}
+
+// signalRestartReady tells the process that restarted this one that it may
+// shut down.
+func signalRestartReady() {
+	loadInherited()
+
+	inherited.mu.Lock()
+	defer inherited.mu.Unlock()
+
+	if inherited.ready == nil {
+		return
+	}
+
+	_, _ = inherited.ready.Write([]byte{1})
+	_ = inherited.ready.Close()
+	inherited.ready = nil
+}
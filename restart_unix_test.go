@@ -0,0 +1,139 @@
+//go:build unix
+
+package httpkit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const restartHelperEnv = "HTTPKIT_RESTART_HELPER"
+
+func generationHandler(name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(name))
+	}
+}
+
+func getBody(t *testing.T, url string) string {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return string(body)
+}
+
+// TestRestart_HelperProcess is the new process started by the handoff tests.
+func TestRestart_HelperProcess(t *testing.T) {
+	if os.Getenv(restartHelperEnv) != "1" {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	handler := NewServer(WithGracefulRestart(RestartConfig{}))
+	handler.Router.Get("/generation", generationHandler("child"))
+	handler.Router.Get("/exit", func(w http.ResponseWriter, r *http.Request) { cancel() })
+
+	if err := handler.Run(ctx); err != nil {
+		os.Exit(1)
+	}
+
+	os.Exit(0)
+}
+
+func TestRestart_Handoff(t *testing.T) {
+	t.Run("should hand the listener off to the new process on SIGUSR2", func(t *testing.T) {
+		t.Setenv(restartHelperEnv, "1")
+
+		args := restartArgs
+		restartArgs = func() []string { return []string{"-test.run=^TestRestart_HelperProcess$"} }
+		t.Cleanup(func() { restartArgs = args })
+
+		handler := NewServer(WithPort(0), WithGracefulRestart(RestartConfig{ReadyTimeout: 10 * time.Second}))
+		handler.Router.Get("/generation", generationHandler("parent"))
+
+		done := runForTest(handler, context.Background())
+
+		require.Eventually(t, func() bool { return handler.Addr() != nil }, time.Second, 5*time.Millisecond)
+
+		url := fmt.Sprintf("http://127.0.0.1:%d", handler.Addr().(*net.TCPAddr).Port)
+		assert.Equal(t, "parent", getBody(t, url+"/generation"))
+
+		require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR2))
+
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(15 * time.Second):
+			t.Fatal("Run did not return after the handoff")
+		}
+
+		assert.Equal(t, "child", getBody(t, url+"/generation"))
+
+		resp, err := http.Get(url + "/exit")
+		require.NoError(t, err)
+		resp.Body.Close()
+	})
+
+	t.Run("should keep the listener when the new process does not become ready", func(t *testing.T) {
+		exe := executable
+		executable = func() (string, error) { return "/bin/true", nil }
+		t.Cleanup(func() { executable = exe })
+
+		args := restartArgs
+		restartArgs = func() []string { return nil }
+		t.Cleanup(func() { restartArgs = args })
+
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer ln.Close()
+
+		handler := NewServer(WithGracefulRestart(RestartConfig{ReadyTimeout: 5 * time.Second}))
+
+		assert.ErrorIs(t, handler.handoff(ln, nil), ErrRestartFailed)
+
+		go func() {
+			_ = handler.serve(ln)
+		}()
+
+		t.Cleanup(func() { _ = handler.server.Close() })
+
+		resp, err := http.Get("http://" + ln.Addr().String() + "/live")
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("should refuse listeners without a file descriptor", func(t *testing.T) {
+		handler := NewServer(WithGracefulRestart(RestartConfig{}))
+
+		assert.ErrorIs(t, handler.handoff(fakeListener{}, nil), ErrRestartFailed)
+	})
+}
+
+func TestRestart_Env(t *testing.T) {
+	t.Run("should drop the variables of a previous handoff", func(t *testing.T) {
+		env := restartEnv([]string{"PATH=/bin", restartFDsEnv + "=http", restartReadyEnv + "=4"})
+
+		assert.Equal(t, []string{"PATH=/bin"}, env)
+	})
+}
+
+type fakeListener struct{ net.Listener }
@@ -0,0 +1,159 @@
+package httpkit
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/philippe-berto/httpkit/utils"
+)
+
+type (
+	// subdomainResolver finds the SubDomain serving a request before routing,
+	// so the global middlewares can apply its policy.
+	subdomainResolver struct {
+		subdomains []*SubDomain
+	}
+
+	subdomainKey struct{}
+)
+
+func newSubdomainResolver(subdomains []*SubDomain) *subdomainResolver {
+	sorted := slices.Clone(subdomains)
+
+	// The longest prefix wins, as with chi's routing.
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i].prefix()) > len(sorted[j].prefix())
+	})
+
+	return &subdomainResolver{subdomains: sorted}
+}
+
+func (s *subdomainResolver) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subdomain := s.resolve(r); subdomain != nil {
+			r = r.WithContext(context.WithValue(r.Context(), subdomainKey{}, subdomain))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *subdomainResolver) resolve(r *http.Request) *SubDomain {
+	path := routePath(r)
+	if slices.Contains(probePaths, path) {
+		return nil
+	}
+
+	var host string
+	if match, ok := utils.HostMatchFromContext(r.Context()); ok {
+		host = match.Pattern
+	}
+
+	for _, subdomain := range s.subdomains {
+		if normalizeHost(subdomain.Host) != host {
+			continue
+		}
+
+		prefix := subdomain.prefix()
+		if prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return subdomain
+		}
+	}
+
+	return nil
+}
+
+func subdomainFromContext(ctx context.Context) *SubDomain {
+	subdomain, _ := ctx.Value(subdomainKey{}).(*SubDomain)
+
+	return subdomain
+}
+
+func (s *SubDomain) prefix() string {
+	return strings.TrimSuffix(s.Domain, "/")
+}
+
+// handler chains Auth and Middlewares in front of Router. It is a router
+// mounting Router rather than a plain handler so that chi.Routes.Find, used
+// by the tracing middleware before routing, still sees the routes.
+func (s *SubDomain) handler() http.Handler {
+	var middlewares chi.Middlewares
+
+	if s.Auth != nil {
+		middlewares = append(middlewares, s.Auth)
+	}
+
+	middlewares = append(middlewares, s.Middlewares...)
+
+	if len(middlewares) == 0 {
+		return s.Router
+	}
+
+	router := chi.NewRouter()
+	router.Use(middlewares...)
+	router.Mount("/", s.Router)
+
+	return router
+}
+
+// unlessSubdomain skips middleware for the requests of the SubDomains for
+// which skip is true.
+func unlessSubdomain(skip func(*SubDomain) bool, middleware func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		wrapped := middleware(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if subdomain := subdomainFromContext(r.Context()); subdomain != nil && skip(subdomain) {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			wrapped.ServeHTTP(w, r)
+		})
+	}
+}
+
+// subdomainBodyLimit applies the body limit of the SubDomain serving the
+// request, or maxBytes.
+func subdomainBodyLimit(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		global := BodyLimit(maxBytes)(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if subdomain := subdomainFromContext(r.Context()); subdomain != nil && subdomain.MaxBodyBytes > 0 {
+				BodyLimit(subdomain.MaxBodyBytes)(next).ServeHTTP(w, r)
+
+				return
+			}
+
+			global.ServeHTTP(w, r)
+		})
+	}
+}
+
+// subdomainCORS applies the CORS policy of the SubDomain serving the request,
+// or global when it has none.
+func subdomainCORS(global *CORSConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			policy := global
+			if subdomain := subdomainFromContext(r.Context()); subdomain != nil && subdomain.CORS != nil {
+				policy = subdomain.CORS
+			}
+
+			if policy == nil || !policy.Enable {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			cors(policy.AllowOrigins)(next).ServeHTTP(w, r)
+		})
+	}
+}
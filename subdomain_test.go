@@ -0,0 +1,241 @@
+package httpkit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/philippe-berto/httpkit/tracing"
+	"github.com/philippe-berto/httpkit/utils"
+)
+
+func headerMiddleware(name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Chain", name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func policyRouter() chi.Router {
+	router := chi.NewRouter()
+	router.Get("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	router.Post("/upload", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		if err := utils.ReadBody(r, &body); err != nil {
+			_ = utils.BodyFault(w, err)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return router
+}
+
+func TestSubDomain_Middlewares(t *testing.T) {
+	t.Run("should run Auth, then Middlewares, only for the SubDomain", func(t *testing.T) {
+		handler := NewServer(
+			WithMiddleware(headerMiddleware("global")),
+			WithSubDomains(
+				&SubDomain{
+					Domain:      "/internal",
+					Router:      policyRouter(),
+					Auth:        headerMiddleware("auth"),
+					Middlewares: []func(http.Handler) http.Handler{headerMiddleware("internal")},
+				},
+				&SubDomain{Domain: "/public", Router: policyRouter()},
+			),
+		)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/internal/ping", nil))
+		assert.Equal(t, []string{"global", "auth", "internal"}, w.Header().Values("X-Chain"))
+
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/public/ping", nil))
+		assert.Equal(t, []string{"global"}, w.Header().Values("X-Chain"))
+	})
+
+	t.Run("should reject requests refused by Auth", func(t *testing.T) {
+		handler := NewServer(WithSubDomains(&SubDomain{
+			Domain: "/internal",
+			Router: policyRouter(),
+			Auth:   RequireClientIdentity(nil),
+		}))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/internal/ping", nil))
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestSubDomain_CORS(t *testing.T) {
+	handler := NewServer(
+		WithCORS("https://app.example.com"),
+		WithSubDomains(
+			&SubDomain{Domain: "/public", Router: policyRouter()},
+			&SubDomain{Domain: "/partner", Router: policyRouter(), CORS: &CORSConfig{Enable: true, AllowOrigins: "https://partner.example.com"}},
+			&SubDomain{Domain: "/internal", Router: policyRouter(), CORS: &CORSConfig{}},
+		),
+	)
+
+	origin := func(path string) string {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		return w.Header().Get("Access-Control-Allow-Origin")
+	}
+
+	t.Run("should inherit the global policy", func(t *testing.T) {
+		assert.Equal(t, "https://app.example.com", origin("/public/ping"))
+	})
+
+	t.Run("should apply the SubDomain policy", func(t *testing.T) {
+		assert.Equal(t, "https://partner.example.com", origin("/partner/ping"))
+		assert.Empty(t, origin("/internal/ping"))
+	})
+
+	t.Run("should apply the policy to preflight requests", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/partner/ping", nil))
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "https://partner.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
+
+func TestSubDomain_BodyLimit(t *testing.T) {
+	handler := NewServer(
+		WithLimits(Limits{MaxBodyBytes: 32}),
+		WithSubDomains(
+			&SubDomain{Domain: "/public", Router: policyRouter()},
+			&SubDomain{Domain: "/uploads", Router: policyRouter(), MaxBodyBytes: 1024},
+		),
+	)
+
+	upload := func(path string) int {
+		body := `{"data":"` + strings.Repeat("x", 100) + `"}`
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+
+		return w.Code
+	}
+
+	t.Run("should apply the global limit", func(t *testing.T) {
+		assert.Equal(t, http.StatusRequestEntityTooLarge, upload("/public/upload"))
+	})
+
+	t.Run("should apply the SubDomain limit instead", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, upload("/uploads/upload"))
+	})
+
+	t.Run("should trace rejected requests", func(t *testing.T) {
+		recorder := tracetest.NewSpanRecorder()
+
+		handler := NewServer(
+			WithLimits(Limits{MaxBodyBytes: 32}),
+			WithTracing(tracing.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))),
+			WithSubDomains(&SubDomain{Domain: "/public", Router: policyRouter()}),
+		)
+
+		body := `{"data":"` + strings.Repeat("x", 100) + `"}`
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/public/upload", strings.NewReader(body)))
+
+		require.Len(t, recorder.Ended(), 1)
+		assert.Contains(t, recorder.Ended()[0].Attributes(), attribute.Int("http.response.status_code", http.StatusRequestEntityTooLarge))
+	})
+}
+
+func TestSubDomain_Instrumentation(t *testing.T) {
+	t.Run("should not trace SubDomains with DisableTracing", func(t *testing.T) {
+		recorder := tracetest.NewSpanRecorder()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+		previous := otel.GetTracerProvider()
+		otel.SetTracerProvider(provider)
+		t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+		handler := NewServer(
+			WithTracing(),
+			WithSubDomains(
+				&SubDomain{Domain: "/public", Router: policyRouter()},
+				&SubDomain{Domain: "/internal", Router: policyRouter(), DisableTracing: true},
+			),
+		)
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/internal/ping", nil))
+		assert.Empty(t, recorder.Ended())
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/public/ping", nil))
+		require.Len(t, recorder.Ended(), 1)
+		assert.Equal(t, "GET /public/ping", recorder.Ended()[0].Name())
+	})
+
+	t.Run("should name spans by route when they start behind SubDomain middlewares and hosts", func(t *testing.T) {
+		recorder := tracetest.NewSpanRecorder()
+		startNames := map[string]string{}
+
+		itemsRouter := func() chi.Router {
+			router := chi.NewRouter()
+			router.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+				startNames[r.Host+r.URL.Path] = trace.SpanFromContext(r.Context()).(sdktrace.ReadOnlySpan).Name()
+			})
+
+			return router
+		}
+
+		handler := NewServer(
+			WithTracing(tracing.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))),
+			WithSubDomains(
+				&SubDomain{Domain: "/api", Router: itemsRouter(), Middlewares: []func(http.Handler) http.Handler{headerMiddleware("api")}},
+				&SubDomain{Host: "*.tenant.example.com", Domain: "/v1", Router: itemsRouter(), Auth: headerMiddleware("auth")},
+			),
+		)
+
+		hostRequest(handler, "example.com", "/api/items/1")
+		hostRequest(handler, "acme.tenant.example.com", "/v1/items/2")
+
+		assert.Equal(t, map[string]string{
+			"example.com/api/items/1":            "GET /api/items/{id}",
+			"acme.tenant.example.com/v1/items/2": "GET /v1/items/{id}",
+		}, startNames)
+		require.Len(t, recorder.Ended(), 2)
+		assert.Contains(t, recorder.Ended()[1].Attributes(), attribute.String("http.route", "/v1/items/{id}"))
+	})
+
+	t.Run("should resolve the SubDomain of a host", func(t *testing.T) {
+		resolver := newSubdomainResolver([]*SubDomain{
+			{Domain: "/v1", Router: policyRouter()},
+			{Host: "api.example.com", Router: policyRouter(), DisableMetrics: true},
+			{Host: "api.example.com", Domain: "/v1", Router: policyRouter()},
+		})
+
+		r := httptest.NewRequest(http.MethodGet, "/v1/ping", nil)
+		r = r.WithContext(utils.ContextWithHostMatch(r.Context(), utils.HostMatch{Pattern: "api.example.com"}))
+		assert.Equal(t, "api.example.com", resolver.resolve(r).Host)
+		assert.False(t, resolver.resolve(r).DisableMetrics)
+
+		r = httptest.NewRequest(http.MethodGet, "/other", nil)
+		r = r.WithContext(utils.ContextWithHostMatch(r.Context(), utils.HostMatch{Pattern: "api.example.com"}))
+		assert.True(t, resolver.resolve(r).DisableMetrics)
+
+		assert.Nil(t, resolver.resolve(httptest.NewRequest(http.MethodGet, "/other", nil)))
+	})
+}
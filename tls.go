@@ -0,0 +1,307 @@
+package httpkit
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultTLSMinVersion     = "1.2"
+	defaultTLSReloadInterval = 30 * time.Second
+
+	ClientAuthNone          = "none"
+	ClientAuthVerifyIfGiven = "verify-if-given"
+	ClientAuthRequire       = "require"
+)
+
+type (
+	TLSConfig struct {
+		CertFile     string   `env:"HTTP_TLS_CERT_FILE"       yaml:"cert_file"`
+		KeyFile      string   `env:"HTTP_TLS_KEY_FILE"        yaml:"key_file"`
+		MinVersion   string   `env:"HTTP_TLS_MIN_VERSION"     envDefault:"1.2" yaml:"min_version"`
+		CipherSuites []string `env:"HTTP_TLS_CIPHER_SUITES"   yaml:"cipher_suites"`
+		// ReloadInterval is how often the files are checked for changes. Zero
+		// means 30 seconds.
+		ReloadInterval time.Duration `env:"HTTP_TLS_RELOAD_INTERVAL" envDefault:"30s" yaml:"reload_interval"`
+		// ClientCAFile enables mutual TLS, verifying client certificates
+		// against the PEM encoded CAs it contains.
+		ClientCAFile string `env:"HTTP_TLS_CLIENT_CA_FILE" yaml:"client_ca_file"`
+		// ClientAuth is one of none, verify-if-given or require. Defaults to
+		// require when ClientCAFile is set.
+		ClientAuth string `env:"HTTP_TLS_CLIENT_AUTH" yaml:"client_auth"`
+	}
+
+	certReloader struct {
+		certFile string
+		keyFile  string
+
+		mu      sync.RWMutex
+		cert    *tls.Certificate
+		modTime time.Time
+	}
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Enabled reports whether a certificate is configured.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+func (c TLSConfig) validate() error {
+	var errs []error
+
+	if c.CertFile == "" || c.KeyFile == "" {
+		errs = append(errs, fmt.Errorf("%w: tls.cert_file and tls.key_file must be set together", ErrInvalidConfig))
+	}
+
+	if _, err := parseTLSVersion(c.MinVersion); err != nil {
+		errs = append(errs, err)
+	}
+
+	if _, err := parseCipherSuites(c.CipherSuites); err != nil {
+		errs = append(errs, err)
+	}
+
+	if _, err := parseClientAuth(c.ClientAuth, c.ClientCAFile); err != nil {
+		errs = append(errs, err)
+	}
+
+	if c.ReloadInterval < 0 {
+		errs = append(errs, fmt.Errorf("%w: tls.reload_interval must not be negative, got %s", ErrInvalidConfig, c.ReloadInterval))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c TLSConfig) build(reloader *certReloader) (*tls.Config, error) {
+	minVersion, err := parseTLSVersion(c.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	cipherSuites, err := parseCipherSuites(c.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+
+	clientAuth, err := parseClientAuth(c.ClientAuth, c.ClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   cipherSuites,
+		GetCertificate: reloader.GetCertificate,
+		ClientAuth:     clientAuth,
+	}
+
+	if c.ClientCAFile != "" {
+		tlsConfig.ClientCAs, err = loadCertPool(c.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// MutualTLS reports whether client certificates are requested.
+func (c TLSConfig) MutualTLS() bool {
+	return c.ClientCAFile != "" && c.ClientAuth != ClientAuthNone
+}
+
+func parseClientAuth(mode, clientCAFile string) (tls.ClientAuthType, error) {
+	if mode == "" && clientCAFile != "" {
+		mode = ClientAuthRequire
+	}
+
+	switch mode {
+	case "", ClientAuthNone:
+		return tls.NoClientCert, nil
+	case ClientAuthVerifyIfGiven, ClientAuthRequire:
+		if clientCAFile == "" {
+			return tls.NoClientCert, fmt.Errorf("%w: tls.client_ca_file is required with tls.client_auth %q", ErrInvalidConfig, mode)
+		}
+
+		if mode == ClientAuthRequire {
+			return tls.RequireAndVerifyClientCert, nil
+		}
+
+		return tls.VerifyClientCertIfGiven, nil
+	default:
+		return tls.NoClientCert, fmt.Errorf("%w: unknown tls.client_auth %q", ErrInvalidConfig, mode)
+	}
+}
+
+func loadCertPool(file string) (*x509.CertPool, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading tls client CA file: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(content) {
+		return nil, fmt.Errorf("tls client CA file %s contains no certificate", file)
+	}
+
+	return pool, nil
+}
+
+func parseTLSVersion(version string) (uint16, error) {
+	if version == "" {
+		version = defaultTLSMinVersion
+	}
+
+	parsed, ok := tlsVersions[version]
+	if !ok {
+		return 0, fmt.Errorf("%w: unknown tls.min_version %q", ErrInvalidConfig, version)
+	}
+
+	return parsed, nil
+}
+
+// parseCipherSuites only accepts the suites Go considers secure. TLS 1.3 suites
+// are not configurable and are always enabled.
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+
+	for _, name := range names {
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown or insecure tls cipher suite %q", ErrInvalidConfig, name)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	reloader := &certReloader{certFile: certFile, keyFile: keyFile}
+
+	if err := reloader.reload(); err != nil {
+		return nil, err
+	}
+
+	return reloader, nil
+}
+
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.cert, nil
+}
+
+func (c *certReloader) reload() error {
+	modTime, err := c.latestModTime()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading tls key pair: %w", err)
+	}
+
+	c.mu.Lock()
+	c.cert = &cert
+	c.modTime = modTime
+	c.mu.Unlock()
+
+	return nil
+}
+
+func (c *certReloader) reloadIfChanged() error {
+	modTime, err := c.latestModTime()
+	if err != nil {
+		return err
+	}
+
+	c.mu.RLock()
+	changed := !modTime.Equal(c.modTime)
+	c.mu.RUnlock()
+
+	if !changed {
+		return nil
+	}
+
+	return c.reload()
+}
+
+func (c *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+
+	for _, file := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("reading tls file: %w", err)
+		}
+
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest, nil
+}
+
+// watch reloads the certificate when the files change on disk or on SIGHUP,
+// until ctx is done. A zero interval disables polling. On failure the
+// previous certificate keeps being served.
+func (c *certReloader) watch(ctx context.Context, interval time.Duration, onError func(error)) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		tick = ticker.C
+	}
+
+	for {
+		var err error
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			err = c.reload()
+		case <-tick:
+			err = c.reloadIfChanged()
+		}
+
+		if err != nil {
+			onError(err)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package httpkit
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSelfSignedCert(t *testing.T, dir string, serial int64) (string, string, *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPath := filepath.Join(dir, "tls.crt")
+	keyPath := filepath.Join(dir, "tls.key")
+
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	// Make every rewrite visible to the modification time check.
+	modTime := time.Now().Add(time.Duration(serial) * time.Second)
+	require.NoError(t, os.Chtimes(certPath, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyPath, modTime, modTime))
+
+	return certPath, keyPath, cert
+}
+
+func serveForTest(t *testing.T, handler *Handler) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- handler.serve(ln)
+	}()
+
+	t.Cleanup(func() {
+		_ = handler.server.Close()
+		<-done
+	})
+
+	return ln.Addr().String()
+}
+
+func servedSerial(t *testing.T, addr string, roots *x509.CertPool) *big.Int {
+	t.Helper()
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: roots, ServerName: "localhost"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	return conn.ConnectionState().PeerCertificates[0].SerialNumber
+}
+
+func TestTLS_Serve(t *testing.T) {
+	t.Run("should serve https and reload the certificate on change", func(t *testing.T) {
+		dir := t.TempDir()
+		certPath, keyPath, first := writeSelfSignedCert(t, dir, 1)
+
+		handler := NewServer(WithTLS(TLSConfig{
+			CertFile:       certPath,
+			KeyFile:        keyPath,
+			MinVersion:     "1.2",
+			ReloadInterval: 10 * time.Millisecond,
+		}))
+		addr := serveForTest(t, handler)
+
+		roots := x509.NewCertPool()
+		roots.AddCert(first)
+
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, ServerName: "localhost"}}}
+		resp, err := client.Get("https://" + addr + "/status")
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, int64(1), servedSerial(t, addr, roots).Int64())
+
+		_, _, second := writeSelfSignedCert(t, dir, 2)
+		roots.AddCert(second)
+
+		assert.Eventually(t, func() bool {
+			return servedSerial(t, addr, roots).Int64() == 2
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("should poll for changes by default", func(t *testing.T) {
+		handler := NewServer(WithTLS(TLSConfig{CertFile: "server.crt", KeyFile: "server.key"}))
+
+		assert.Equal(t, defaultTLSReloadInterval, handler.tls.ReloadInterval)
+	})
+
+	t.Run("should fail to start with a missing certificate", func(t *testing.T) {
+		handler := NewServer(WithTLS(TLSConfig{CertFile: "missing.crt", KeyFile: "missing.key"}))
+
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		assert.Error(t, handler.serve(ln))
+	})
+}
+
+func TestTLS_Validate(t *testing.T) {
+	t.Run("should reject incomplete and insecure settings", func(t *testing.T) {
+		err := TLSConfig{CertFile: "tls.crt", MinVersion: "1.4", CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}}.validate()
+
+		require.ErrorIs(t, err, ErrInvalidConfig)
+		assert.Contains(t, err.Error(), "must be set together")
+		assert.Contains(t, err.Error(), `unknown tls.min_version "1.4"`)
+		assert.Contains(t, err.Error(), `insecure tls cipher suite "TLS_RSA_WITH_RC4_128_SHA"`)
+	})
+}
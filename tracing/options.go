@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type (
+	// Option configures the middleware built by New.
+	Option func(*config)
+
+	config struct {
+		provider   trace.TracerProvider
+		propagator propagation.TextMapPropagator
+		spanName   func(r *http.Request) string
+		enrichers  []func(r *http.Request) []attribute.KeyValue
+		filters    []func(r *http.Request) bool
+		sampling   SamplingPolicy
+	}
+)
+
+func defaultConfig() config {
+	return config{spanName: RouteSpanName}
+}
+
+// WithTracerProvider creates spans with provider instead of the global one.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.provider = provider
+	}
+}
+
+// WithPropagator extracts the incoming trace context with propagator instead
+// of the global one.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagator = propagator
+	}
+}
+
+// WithSpanNameFormatter names the spans with format, called when the request
+// arrives and again once it is routed. Defaults to RouteSpanName.
+func WithSpanNameFormatter(format func(r *http.Request) string) Option {
+	return func(c *config) {
+		c.spanName = format
+	}
+}
+
+// WithAttributes adds the attributes returned by enrich, called once the
+// request is served, to every span.
+func WithAttributes(enrich func(r *http.Request) []attribute.KeyValue) Option {
+	return func(c *config) {
+		c.enrichers = append(c.enrichers, enrich)
+	}
+}
+
+// WithFilter traces only the requests for which filter returns true. Probe
+// and metrics paths are never traced.
+func WithFilter(filter func(r *http.Request) bool) Option {
+	return func(c *config) {
+		c.filters = append(c.filters, filter)
+	}
+}
+
+// WithSampling forces the sampling of some requests, see SamplingPolicy. It
+// has no effect unless the provider is built by Setup, see CheckOptions.
+func WithSampling(policy SamplingPolicy) Option {
+	return func(c *config) {
+		c.sampling = policy
+	}
+}
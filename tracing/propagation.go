@@ -0,0 +1,56 @@
+package tracing
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/contrib/propagators/jaeger"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const (
+	// PropagatorTraceContext is the W3C traceparent and tracestate headers.
+	PropagatorTraceContext = "tracecontext"
+	// PropagatorBaggage is the W3C baggage header.
+	PropagatorBaggage = "baggage"
+	// PropagatorB3 is the single b3 header of Zipkin.
+	PropagatorB3 = "b3"
+	// PropagatorB3Multi is the X-B3-* headers of Zipkin.
+	PropagatorB3Multi = "b3multi"
+	// PropagatorJaeger is the uber-trace-id header of Jaeger.
+	PropagatorJaeger = "jaeger"
+)
+
+var ErrUnknownPropagator = errors.New("unknown propagator")
+
+// DefaultPropagators are the W3C trace context and baggage.
+func DefaultPropagators() []string {
+	return []string{PropagatorTraceContext, PropagatorBaggage}
+}
+
+// NewPropagator combines the named propagators, in the order given. Incoming
+// requests are extracted with each of them, later ones winning.
+func NewPropagator(names ...string) (propagation.TextMapPropagator, error) {
+	propagators := make([]propagation.TextMapPropagator, 0, len(names))
+
+	for _, name := range names {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case PropagatorTraceContext:
+			propagators = append(propagators, propagation.TraceContext{})
+		case PropagatorBaggage:
+			propagators = append(propagators, propagation.Baggage{})
+		case PropagatorB3:
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3SingleHeader)))
+		case PropagatorB3Multi:
+			propagators = append(propagators, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+		case PropagatorJaeger:
+			propagators = append(propagators, jaeger.Jaeger{})
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrUnknownPropagator, name)
+		}
+	}
+
+	return propagation.NewCompositeTextMapPropagator(propagators...), nil
+}
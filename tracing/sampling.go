@@ -0,0 +1,205 @@
+package tracing
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// SampleRatio samples Ratio of the new traces, and follows the parent of
+	// the others with the parent-based samplers. It is the default.
+	SampleRatio = "ratio"
+	// SampleAlways samples every request, even under an unsampled parent.
+	SampleAlways = "always"
+	// SampleNever samples no request, even under a sampled parent, such as
+	// the health checks of a mesh starting the traces.
+	SampleNever = "never"
+)
+
+// SamplingPriorityKey forces the sampling of the spans started with a
+// positive value, whatever the rules and the parent. The middleware sets it
+// for the requests forced by its SamplingPolicy.
+const SamplingPriorityKey = attribute.Key("sampling.priority")
+
+// ErrSamplingUnsupported reports a SamplingPolicy given with a TracerProvider
+// whose sampler may not honor SamplingPriorityKey.
+var ErrSamplingUnsupported = errors.New("sampling policy needs a provider built by tracing.Setup")
+
+type (
+	// SamplingRule decides the sampling of the requests matching Route, a chi route pattern such as "/items/{id}", and Method.
+	// Empty fields match any request. The first matching rule wins.
+	SamplingRule struct {
+		Route  string `yaml:"route"`
+		Method string `yaml:"method"`
+		// Sample is SampleRatio, SampleAlways or SampleNever. Empty means
+		// SampleRatio.
+		Sample string  `yaml:"sample"`
+		Ratio  float64 `yaml:"ratio"`
+	}
+
+	// SamplingPolicy forces the sampling of some requests by starting their
+	// span with SamplingPriorityKey, which only the samplers built by
+	// NewSampler honor, as with Setup. Other providers still drop the spans
+	// their sampler leaves out, see CheckOptions.
+	SamplingPolicy struct {
+		// DebugHeader samples the requests carrying this header.
+		DebugHeader string `env:"TRACING_DEBUG_HEADER" yaml:"debug_header"`
+		// SampleErrors samples the requests left out by the sampler that end
+		// with a 5xx status. Their span is only started once the handler
+		// returns, so it has no children.
+		SampleErrors bool `env:"TRACING_SAMPLE_ERRORS" yaml:"sample_errors"`
+	}
+
+	ruleSampler struct {
+		rules    []SamplingRule
+		samplers []sdktrace.Sampler
+		fallback sdktrace.Sampler
+	}
+
+	prioritySampler struct {
+		next sdktrace.Sampler
+	}
+)
+
+// NewSampler builds the sampler cfg describes. Its rules match the route and
+// method the middleware sets when starting the span, and it samples the spans
+// forced by SamplingPriorityKey.
+func NewSampler(cfg SamplerConfig) sdktrace.Sampler {
+	var root sdktrace.Sampler
+
+	switch cfg.Type {
+	case SamplerAlwaysOff, SamplerParentBasedAlwaysOff:
+		root = sdktrace.NeverSample()
+	case SamplerRatio, SamplerParentBasedRatio:
+		root = sdktrace.TraceIDRatioBased(cfg.Ratio)
+	default:
+		root = sdktrace.AlwaysSample()
+	}
+
+	follow := func(s sdktrace.Sampler) sdktrace.Sampler { return sdktrace.ParentBased(s) }
+	if cfg.Type == SamplerAlwaysOn || cfg.Type == SamplerAlwaysOff || cfg.Type == SamplerRatio {
+		follow = func(s sdktrace.Sampler) sdktrace.Sampler { return s }
+	}
+
+	if len(cfg.Rules) == 0 {
+		return prioritySampler{next: follow(root)}
+	}
+
+	rules := ruleSampler{rules: cfg.Rules, fallback: follow(root)}
+	for _, rule := range cfg.Rules {
+		rules.samplers = append(rules.samplers, rule.sampler(follow))
+	}
+
+	return prioritySampler{next: rules}
+}
+
+// CheckOptions reports ErrSamplingUnsupported when opts set a SamplingPolicy
+// with a TracerProvider other than a Provider built by Setup, since New cannot
+// report it. Without WithTracerProvider, it checks the global provider at call
+// time.
+func CheckOptions(opts ...Option) error {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.sampling == (SamplingPolicy{}) {
+		return nil
+	}
+
+	if _, ok := cfg.tracerProvider().(*Provider); ok {
+		return nil
+	}
+
+	return ErrSamplingUnsupported
+}
+
+func validateRules(field string, rules []SamplingRule) error {
+	var errs []error
+
+	for i, rule := range rules {
+		switch rule.Sample {
+		case "", SampleRatio, SampleAlways, SampleNever:
+		default:
+			errs = append(errs, fmt.Errorf("%w: %s[%d].sample must be ratio, always or never, got %q", ErrInvalidSetup, field, i, rule.Sample))
+		}
+
+		if rule.Ratio < 0 || rule.Ratio > 1 {
+			errs = append(errs, fmt.Errorf("%w: %s[%d].ratio must be between 0 and 1, got %g", ErrInvalidSetup, field, i, rule.Ratio))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (rule SamplingRule) matches(route, method string) bool {
+	return (rule.Route == "" || rule.Route == route) && (rule.Method == "" || strings.EqualFold(rule.Method, method))
+}
+
+// sampler applies always and never rules whatever the parent, and ratio rules
+// through follow, which makes them follow the parent with the parent-based
+// samplers.
+func (rule SamplingRule) sampler(follow func(sdktrace.Sampler) sdktrace.Sampler) sdktrace.Sampler {
+	switch rule.Sample {
+	case SampleAlways:
+		return sdktrace.AlwaysSample()
+	case SampleNever:
+		return sdktrace.NeverSample()
+	default:
+		return follow(sdktrace.TraceIDRatioBased(rule.Ratio))
+	}
+}
+
+func (s ruleSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	var route, method string
+
+	for _, attr := range p.Attributes {
+		switch attr.Key {
+		case semconv.HTTPRouteKey:
+			route = attr.Value.AsString()
+		case semconv.HTTPRequestMethodKey:
+			method = attr.Value.AsString()
+		}
+	}
+
+	for i, rule := range s.rules {
+		if rule.matches(route, method) {
+			return s.samplers[i].ShouldSample(p)
+		}
+	}
+
+	return s.fallback.ShouldSample(p)
+}
+
+func (s ruleSampler) Description() string {
+	return fmt.Sprintf("RouteRules{rules:%d,fallback:%s}", len(s.rules), s.fallback.Description())
+}
+
+func (s prioritySampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	for _, attr := range p.Attributes {
+		if attr.Key == SamplingPriorityKey && attr.Value.AsInt64() > 0 {
+			return sdktrace.SamplingResult{
+				Decision:   sdktrace.RecordAndSample,
+				Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+			}
+		}
+	}
+
+	return s.next.ShouldSample(p)
+}
+
+func (s prioritySampler) Description() string {
+	return fmt.Sprintf("Priority{%s}", s.next.Description())
+}
+
+// forced reports whether the request carries the debug header.
+func (p SamplingPolicy) forced(r *http.Request) bool {
+	return p.DebugHeader != "" && r.Header.Get(p.DebugHeader) != ""
+}
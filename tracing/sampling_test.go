@@ -0,0 +1,118 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing_Sampling(t *testing.T) {
+	var handled trace.SpanContext
+	var member string
+
+	newRouter := func(sampler SamplerConfig, policy SamplingPolicy) (http.Handler, *tracetest.SpanRecorder) {
+		recorder := tracetest.NewSpanRecorder()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSampler(NewSampler(sampler)), sdktrace.WithSpanProcessor(recorder))
+
+		router := chi.NewRouter()
+		router.Use(New(WithTracerProvider(provider), WithPropagator(mustPropagator(t)), WithSampling(policy)))
+		router.Get("/poll", func(w http.ResponseWriter, r *http.Request) {
+			handled = trace.SpanContextFromContext(r.Context())
+			member = baggage.FromContext(r.Context()).Member("tenant").Value()
+		})
+		router.Get("/fail", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		})
+
+		return router, recorder
+	}
+
+	get := func(router http.Handler, path string, headers map[string]string) {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		for key, value := range headers {
+			r.Header.Set(key, value)
+		}
+
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	never := SamplerConfig{Type: SamplerParentBasedRatio, Ratio: 0}
+
+	t.Run("should keep the caller context on requests left out", func(t *testing.T) {
+		router, recorder := newRouter(SamplerConfig{Rules: []SamplingRule{{Route: "/poll", Sample: SampleNever}}}, SamplingPolicy{})
+
+		get(router, "/poll", map[string]string{
+			"traceparent": "00-" + parentTraceID + "-" + parentSpanID + "-00",
+			"baggage":     "tenant=acme",
+		})
+
+		assert.Empty(t, recorder.Ended())
+		assert.Equal(t, parentTraceID, handled.TraceID().String())
+		assert.False(t, handled.IsSampled())
+		assert.Equal(t, "acme", member)
+	})
+
+	t.Run("should trace requests carrying the debug header", func(t *testing.T) {
+		router, recorder := newRouter(never, SamplingPolicy{DebugHeader: "X-Debug-Trace"})
+
+		get(router, "/poll", nil)
+		assert.Empty(t, recorder.Ended())
+
+		get(router, "/poll", map[string]string{
+			"X-Debug-Trace": "1",
+			"traceparent":   "00-" + parentTraceID + "-" + parentSpanID + "-00",
+		})
+		require.Len(t, recorder.Ended(), 1)
+		assert.Equal(t, parentTraceID, recorder.Ended()[0].SpanContext().TraceID().String())
+		assert.True(t, handled.IsSampled())
+	})
+
+	t.Run("should trace the server errors of requests left out", func(t *testing.T) {
+		router, recorder := newRouter(never, SamplingPolicy{SampleErrors: true})
+
+		get(router, "/poll", nil)
+		assert.Empty(t, recorder.Ended())
+
+		get(router, "/fail", nil)
+		require.Len(t, recorder.Ended(), 1)
+
+		span := recorder.Ended()[0]
+		assert.Equal(t, "GET /fail", span.Name())
+		assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+		assert.Contains(t, span.Attributes(), attribute.Int("http.response.status_code", http.StatusBadGateway))
+		assert.False(t, span.StartTime().After(span.EndTime()))
+	})
+
+	t.Run("should not force spans without a positive priority", func(t *testing.T) {
+		sampler := NewSampler(never)
+
+		result := sampler.ShouldSample(sdktrace.SamplingParameters{Attributes: []attribute.KeyValue{SamplingPriorityKey.Int(0)}})
+		assert.Equal(t, sdktrace.Drop, result.Decision)
+
+		result = sampler.ShouldSample(sdktrace.SamplingParameters{Attributes: []attribute.KeyValue{SamplingPriorityKey.Int(1)}})
+		assert.Equal(t, sdktrace.RecordAndSample, result.Decision)
+	})
+
+	t.Run("should reject a policy the provider does not honor", func(t *testing.T) {
+		provider, err := Setup(context.Background(), SetupConfig{Exporter: ExporterMemory})
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+		policy := WithSampling(SamplingPolicy{DebugHeader: "X-Debug-Trace"})
+
+		assert.NoError(t, CheckOptions(WithTracerProvider(provider), policy))
+		assert.NoError(t, CheckOptions(WithTracerProvider(sdktrace.NewTracerProvider())))
+		assert.ErrorIs(t, CheckOptions(WithTracerProvider(sdktrace.NewTracerProvider()), policy), ErrSamplingUnsupported)
+		assert.ErrorIs(t, CheckOptions(policy), ErrSamplingUnsupported)
+	})
+}
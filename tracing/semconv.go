@@ -0,0 +1,147 @@
+package tracing
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+
+	"github.com/philippe-berto/httpkit/utils"
+)
+
+var knownMethods = map[string]bool{
+	http.MethodConnect: true,
+	http.MethodDelete:  true,
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPatch:   true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodTrace:   true,
+}
+
+// Route returns the chi route pattern matching r, such as "/items/{id}". It
+// is known before the request is served as long as the route is registered on
+// the router running the middleware or on the router of its host, and empty
+// when no route matches.
+func Route(r *http.Request) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return ""
+	}
+
+	if pattern := rctx.RoutePattern(); pattern != "" {
+		return pattern
+	}
+
+	routes := rctx.Routes
+
+	// Requests routed by host are served by the router of their host.
+	if match, ok := utils.HostMatchFromContext(r.Context()); ok && match.Routes != nil {
+		routes = match.Routes
+	}
+
+	if routes == nil {
+		return ""
+	}
+
+	path := rctx.RoutePath
+	if path == "" {
+		path = r.URL.Path
+	}
+
+	// Find updates the context it is given, so it gets a fresh one.
+	return routes.Find(chi.NewRouteContext(), r.Method, path)
+}
+
+// RouteSpanName names the span "{method} {route}", such as
+// "GET /items/{id}", or only after the method when no route matches, as the
+// OpenTelemetry HTTP semantic conventions recommend.
+func RouteSpanName(r *http.Request) string {
+	method := r.Method
+	if !knownMethods[method] {
+		method = "HTTP"
+	}
+
+	if route := Route(r); route != "" {
+		return method + " " + route
+	}
+
+	return method
+}
+
+// requestAttributes are known when the request arrives, so samplers see them.
+func requestAttributes(r *http.Request) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.URLPath(r.URL.Path),
+		semconv.URLScheme(utils.Scheme(r)),
+		semconv.ClientAddress(utils.ClientIP(r)),
+		semconv.NetworkProtocolVersion(utils.ProtocolVersion(r)),
+	}
+
+	if knownMethods[r.Method] {
+		attrs = append(attrs, semconv.HTTPRequestMethodKey.String(r.Method))
+	} else {
+		attrs = append(attrs, semconv.HTTPRequestMethodOther, semconv.HTTPRequestMethodOriginal(r.Method))
+	}
+
+	host, port := splitHostPort(utils.Host(r))
+	if host != "" {
+		attrs = append(attrs, semconv.ServerAddress(host))
+	}
+
+	if port > 0 {
+		attrs = append(attrs, semconv.ServerPort(port))
+	}
+
+	if userAgent := r.UserAgent(); userAgent != "" {
+		attrs = append(attrs, semconv.UserAgentOriginal(userAgent))
+	}
+
+	if r.ContentLength > 0 {
+		attrs = append(attrs, semconv.HTTPRequestBodySize(int(r.ContentLength)))
+	}
+
+	if route := Route(r); route != "" {
+		attrs = append(attrs, semconv.HTTPRoute(route))
+	}
+
+	return attrs
+}
+
+// responseAttributes are known once the request is served. The route is set
+// again as it may only be known after routing, on a sub-router or host router.
+func responseAttributes(r *http.Request, ww *utils.StatusWriter) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.HTTPResponseStatusCode(ww.StatusCode),
+		semconv.HTTPResponseBodySize(ww.Bytes),
+	}
+
+	if route := Route(r); route != "" {
+		attrs = append(attrs, semconv.HTTPRoute(route))
+	}
+
+	if hostMatch, ok := utils.HostMatchFromContext(r.Context()); ok {
+		attrs = append(attrs, attribute.Key("host_pattern").String(hostMatch.Pattern))
+	}
+
+	return attrs
+}
+
+func splitHostPort(hostport string) (string, int) {
+	host, portStr, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return hostport, 0
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return host, 0
+	}
+
+	return host, port
+}
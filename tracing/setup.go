@@ -0,0 +1,267 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+const (
+	// ExporterNone records spans for propagation without exporting them.
+	ExporterNone = "none"
+	// ExporterOTLPGRPC sends spans to an OTLP collector over gRPC, by default
+	// on localhost:4317.
+	ExporterOTLPGRPC = "otlp-grpc"
+	// ExporterOTLPHTTP sends spans to an OTLP collector over HTTP, by default
+	// on localhost:4318.
+	ExporterOTLPHTTP = "otlp-http"
+	// ExporterStdout prints spans as indented JSON on the standard output.
+	ExporterStdout = "stdout"
+	// ExporterFile appends spans as JSON lines to a file.
+	ExporterFile = "file"
+	// ExporterMemory keeps spans in memory, see Provider.Spans.
+	ExporterMemory = "memory"
+)
+
+const (
+	SamplerAlwaysOn             = "always_on"
+	SamplerAlwaysOff            = "always_off"
+	SamplerRatio                = "traceidratio"
+	SamplerParentBasedAlwaysOn  = "parentbased_always_on"
+	SamplerParentBasedAlwaysOff = "parentbased_always_off"
+	SamplerParentBasedRatio     = "parentbased_traceidratio"
+)
+
+var ErrInvalidSetup = errors.New("invalid tracing setup")
+
+type (
+	// SetupConfig describes the TracerProvider built by Setup.
+	SetupConfig struct {
+		ServiceName    string `env:"TRACING_SERVICE_NAME"    yaml:"service_name"`
+		ServiceVersion string `env:"TRACING_SERVICE_VERSION" yaml:"service_version"`
+		Environment    string `env:"TRACING_ENVIRONMENT"     yaml:"environment"`
+		// Exporter is one of the Exporter constants. Empty means ExporterNone.
+		Exporter string `env:"TRACING_EXPORTER" yaml:"exporter"`
+		// Endpoint is the host:port or URL of the OTLP collector. Empty leaves
+		// it to the OTEL_EXPORTER_OTLP_* variables or the exporter default.
+		Endpoint string            `env:"TRACING_ENDPOINT" yaml:"endpoint"`
+		Insecure bool              `env:"TRACING_INSECURE" yaml:"insecure"`
+		Headers  map[string]string `env:"TRACING_HEADERS"  yaml:"headers"`
+		// FilePath is the file written by ExporterFile.
+		FilePath string        `env:"TRACING_FILE_PATH" yaml:"file_path"`
+		Sampler  SamplerConfig `yaml:"sampler"`
+	}
+
+	// SamplerConfig mirrors the OTEL_TRACES_SAMPLER variables, with rules
+	// sampling some routes differently. Traces continued from a sampled
+	// parent follow the parent with the parent-based samplers. See
+	// NewSampler.
+	SamplerConfig struct {
+		// Type is one of the Sampler constants. Empty means
+		// SamplerParentBasedAlwaysOn.
+		Type string `env:"TRACING_SAMPLER" yaml:"type"`
+		// Ratio of the traces sampled by the ratio samplers, from 0 to 1.
+		Ratio float64        `env:"TRACING_SAMPLER_RATIO" envDefault:"1" yaml:"ratio"`
+		Rules []SamplingRule `yaml:"rules"`
+	}
+
+	// Provider is the TracerProvider built by Setup. Its Shutdown flushes the
+	// pending spans and must be called before exiting, for instance from
+	// Handler.OnShutdown.
+	Provider struct {
+		*sdktrace.TracerProvider
+		memory *tracetest.InMemoryExporter
+		file   *os.File
+	}
+)
+
+// Validate reports every invalid field of the config at once.
+func (c SetupConfig) Validate() error {
+	var errs []error
+
+	switch c.Exporter {
+	case "", ExporterNone, ExporterOTLPGRPC, ExporterOTLPHTTP, ExporterStdout, ExporterMemory:
+	case ExporterFile:
+		if c.FilePath == "" {
+			errs = append(errs, fmt.Errorf("%w: file_path is required by the file exporter", ErrInvalidSetup))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%w: unknown exporter %q", ErrInvalidSetup, c.Exporter))
+	}
+
+	switch c.Sampler.Type {
+	case "", SamplerAlwaysOn, SamplerAlwaysOff, SamplerRatio,
+		SamplerParentBasedAlwaysOn, SamplerParentBasedAlwaysOff, SamplerParentBasedRatio:
+	default:
+		errs = append(errs, fmt.Errorf("%w: unknown sampler %q", ErrInvalidSetup, c.Sampler.Type))
+	}
+
+	if c.Sampler.Ratio < 0 || c.Sampler.Ratio > 1 {
+		errs = append(errs, fmt.Errorf("%w: sampler.ratio must be between 0 and 1, got %g", ErrInvalidSetup, c.Sampler.Ratio))
+	}
+
+	if err := validateRules("sampler.rules", c.Sampler.Rules); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Setup builds a TracerProvider exporting the spans as cfg describes. It
+// does not replace the global provider: pass it to WithTracerProvider or
+// otel.SetTracerProvider.
+func Setup(ctx context.Context, cfg SetupConfig) (*Provider, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	p := &Provider{}
+
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(cfg.resource()),
+		sdktrace.WithSampler(NewSampler(cfg.Sampler)),
+	}
+
+	switch cfg.Exporter {
+	case ExporterOTLPGRPC:
+		exporter, err := otlptracegrpc.New(ctx, cfg.grpcOptions()...)
+		if err != nil {
+			return nil, fmt.Errorf("creating otlp grpc exporter: %w", err)
+		}
+
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	case ExporterOTLPHTTP:
+		exporter, err := otlptracehttp.New(ctx, cfg.httpOptions()...)
+		if err != nil {
+			return nil, fmt.Errorf("creating otlp http exporter: %w", err)
+		}
+
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	case ExporterStdout:
+		exporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, fmt.Errorf("creating stdout exporter: %w", err)
+		}
+
+		opts = append(opts, sdktrace.WithSyncer(exporter))
+	case ExporterFile:
+		file, err := os.OpenFile(cfg.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("opening trace file: %w", err)
+		}
+
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			_ = file.Close()
+
+			return nil, fmt.Errorf("creating file exporter: %w", err)
+		}
+
+		p.file = file
+		opts = append(opts, sdktrace.WithBatcher(exporter))
+	case ExporterMemory:
+		p.memory = tracetest.NewInMemoryExporter()
+		opts = append(opts, sdktrace.WithSyncer(p.memory))
+	}
+
+	p.TracerProvider = sdktrace.NewTracerProvider(opts...)
+
+	return p, nil
+}
+
+// Shutdown flushes the pending spans and stops the exporter.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	err := p.TracerProvider.Shutdown(ctx)
+
+	if p.file != nil {
+		err = errors.Join(err, p.file.Close())
+	}
+
+	return err
+}
+
+// Spans returns the spans exported so far by ExporterMemory, nil with any
+// other exporter.
+func (p *Provider) Spans() tracetest.SpanStubs {
+	if p.memory == nil {
+		return nil
+	}
+
+	return p.memory.GetSpans()
+}
+
+// resource describes the service on top of the SDK defaults, which include
+// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES.
+func (c SetupConfig) resource() *resource.Resource {
+	var attrs []attribute.KeyValue
+
+	if c.ServiceName != "" {
+		attrs = append(attrs, semconv.ServiceName(c.ServiceName))
+	}
+
+	if c.ServiceVersion != "" {
+		attrs = append(attrs, semconv.ServiceVersion(c.ServiceVersion))
+	}
+
+	if c.Environment != "" {
+		attrs = append(attrs, semconv.DeploymentEnvironment(c.Environment))
+	}
+
+	// A schemaless resource always merges.
+	res, _ := resource.Merge(resource.Default(), resource.NewSchemaless(attrs...))
+
+	return res
+}
+
+func (c SetupConfig) grpcOptions() []otlptracegrpc.Option {
+	var opts []otlptracegrpc.Option
+
+	switch {
+	case strings.Contains(c.Endpoint, "://"):
+		opts = append(opts, otlptracegrpc.WithEndpointURL(c.Endpoint))
+	case c.Endpoint != "":
+		opts = append(opts, otlptracegrpc.WithEndpoint(c.Endpoint))
+	}
+
+	if c.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+
+	if len(c.Headers) > 0 {
+		opts = append(opts, otlptracegrpc.WithHeaders(c.Headers))
+	}
+
+	return opts
+}
+
+func (c SetupConfig) httpOptions() []otlptracehttp.Option {
+	var opts []otlptracehttp.Option
+
+	switch {
+	case strings.Contains(c.Endpoint, "://"):
+		opts = append(opts, otlptracehttp.WithEndpointURL(c.Endpoint))
+	case c.Endpoint != "":
+		opts = append(opts, otlptracehttp.WithEndpoint(c.Endpoint))
+	}
+
+	if c.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+
+	if len(c.Headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(c.Headers))
+	}
+
+	return opts
+}
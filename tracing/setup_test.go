@@ -0,0 +1,187 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing_Setup(t *testing.T) {
+	serve := func(t *testing.T, provider trace.TracerProvider, method, path string, headers map[string]string) {
+		t.Helper()
+
+		router := chi.NewRouter()
+		router.Use(New(WithTracerProvider(provider), WithPropagator(mustPropagator(t))))
+		router.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {})
+		router.Post("/items/{id}", func(w http.ResponseWriter, r *http.Request) {})
+		router.Get("/poll", func(w http.ResponseWriter, r *http.Request) {})
+		router.Post("/poll", func(w http.ResponseWriter, r *http.Request) {})
+
+		r := httptest.NewRequest(method, path, nil)
+		for key, value := range headers {
+			r.Header.Set(key, value)
+		}
+
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	t.Run("should describe the service in the resource", func(t *testing.T) {
+		provider, err := Setup(context.Background(), SetupConfig{
+			ServiceName:    "orders",
+			ServiceVersion: "1.2.3",
+			Environment:    "staging",
+			Exporter:       ExporterMemory,
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+		serve(t, provider, http.MethodGet, "/items/42", nil)
+
+		spans := provider.Spans()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "GET /items/{id}", spans[0].Name)
+		assert.Subset(t, spans[0].Resource.Attributes(), []attribute.KeyValue{
+			attribute.String("service.name", "orders"),
+			attribute.String("service.version", "1.2.3"),
+			attribute.String("deployment.environment", "staging"),
+		})
+	})
+
+	t.Run("should apply the first matching sampling rule", func(t *testing.T) {
+		provider, err := Setup(context.Background(), SetupConfig{
+			Exporter: ExporterMemory,
+			Sampler: SamplerConfig{
+				Type: SamplerParentBasedAlwaysOn,
+				Rules: []SamplingRule{
+					{Route: "/poll", Method: http.MethodGet, Sample: SampleNever},
+				},
+			},
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+		serve(t, provider, http.MethodGet, "/poll", nil)
+		assert.Empty(t, provider.Spans())
+
+		serve(t, provider, http.MethodPost, "/poll", nil)
+		serve(t, provider, http.MethodGet, "/items/42", nil)
+		assert.Len(t, provider.Spans(), 2)
+	})
+
+	t.Run("should apply always and never rules whatever the parent", func(t *testing.T) {
+		provider, err := Setup(context.Background(), SetupConfig{
+			Exporter: ExporterMemory,
+			Sampler: SamplerConfig{
+				Type: SamplerParentBasedRatio,
+				Rules: []SamplingRule{
+					{Route: "/poll", Sample: SampleNever},
+					{Route: "/items/{id}", Method: http.MethodPost, Sample: SampleAlways},
+					{Route: "/items/{id}", Ratio: 0},
+				},
+			},
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+		sampled := map[string]string{"traceparent": "00-" + parentTraceID + "-" + parentSpanID + "-01"}
+		unsampled := map[string]string{"traceparent": "00-" + parentTraceID + "-" + parentSpanID + "-00"}
+
+		serve(t, provider, http.MethodGet, "/poll", sampled)
+		assert.Empty(t, provider.Spans())
+
+		serve(t, provider, http.MethodPost, "/items/42", unsampled)
+		require.Len(t, provider.Spans(), 1)
+
+		serve(t, provider, http.MethodGet, "/items/42", unsampled)
+		serve(t, provider, http.MethodGet, "/items/42", sampled)
+		require.Len(t, provider.Spans(), 2)
+		assert.Equal(t, parentTraceID, provider.Spans()[1].SpanContext.TraceID().String())
+	})
+
+	t.Run("should sample the configured ratio", func(t *testing.T) {
+		provider, err := Setup(context.Background(), SetupConfig{
+			Exporter: ExporterMemory,
+			Sampler:  SamplerConfig{Type: SamplerRatio, Ratio: 0},
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+		serve(t, provider, http.MethodGet, "/items/42", map[string]string{
+			"traceparent": "00-" + parentTraceID + "-" + parentSpanID + "-01",
+		})
+		assert.Empty(t, provider.Spans())
+	})
+
+	t.Run("should append spans to a file as JSON lines", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "traces.json")
+
+		provider, err := Setup(context.Background(), SetupConfig{Exporter: ExporterFile, FilePath: path})
+		require.NoError(t, err)
+
+		serve(t, provider, http.MethodGet, "/items/42", nil)
+		require.NoError(t, provider.Shutdown(context.Background()))
+		assert.Nil(t, provider.Spans())
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+
+		var span struct{ Name string }
+		require.NoError(t, json.Unmarshal(content, &span))
+		assert.Equal(t, "GET /items/{id}", span.Name)
+	})
+
+	t.Run("should build the otlp exporters without connecting", func(t *testing.T) {
+		for _, exporter := range []string{ExporterOTLPGRPC, ExporterOTLPHTTP} {
+			provider, err := Setup(context.Background(), SetupConfig{
+				Exporter: exporter,
+				Endpoint: "http://127.0.0.1:1",
+				Headers:  map[string]string{"authorization": "token"},
+			})
+			require.NoError(t, err, exporter)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			_ = provider.Shutdown(ctx)
+		}
+	})
+
+	t.Run("should report every invalid field", func(t *testing.T) {
+		_, err := Setup(context.Background(), SetupConfig{
+			Exporter: "zipkin",
+			Sampler: SamplerConfig{
+				Type:  "sometimes",
+				Ratio: 2,
+				Rules: []SamplingRule{{Route: "/poll", Ratio: -1}},
+			},
+		})
+		require.ErrorIs(t, err, ErrInvalidSetup)
+		assert.Contains(t, err.Error(), `unknown exporter "zipkin"`)
+		assert.Contains(t, err.Error(), `unknown sampler "sometimes"`)
+		assert.Contains(t, err.Error(), "sampler.ratio must be between 0 and 1, got 2")
+		assert.Contains(t, err.Error(), "sampler.rules[0].ratio must be between 0 and 1, got -1")
+
+		err = SetupConfig{Exporter: ExporterFile}.Validate()
+		assert.ErrorContains(t, err, "file_path is required")
+	})
+}
+
+func mustPropagator(t *testing.T) propagation.TextMapPropagator {
+	t.Helper()
+
+	propagator, err := NewPropagator(DefaultPropagators()...)
+	require.NoError(t, err)
+
+	return propagator
+}
@@ -2,55 +2,122 @@ package tracing
 
 import (
 	"net/http"
+	"time"
 
-	"github.com/go-chi/chi/v5"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/baggage"
-	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/philippe-berto/httpkit/utils"
 )
 
 const (
-	tracerName = "github.com/AudioStreamTV/api-v2-package/tracing"
+	tracerName = "github.com/philippe-berto/httpkit/tracing"
 )
 
+// TracingMiddleware is New without options: it uses the global
+// TracerProvider and TextMapPropagator.
 func TracingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if utils.CheckInValidPath(r) {
-			next.ServeHTTP(w, r)
+	return New()(next)
+}
+
+// New builds a middleware starting a server span for each request,
+// continuing the trace of the caller as extracted by the propagator, which
+// also makes the incoming baggage available to the handler. Without
+// WithTracerProvider or WithPropagator, it uses the globals at request time.
+// Probe and metrics paths are never traced; the sampler of the provider
+// decides for the others, see NewSampler and WithSampling.
+func New(opts ...Option) func(http.Handler) http.Handler {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !cfg.traced(r) {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			parentCtx := cfg.textMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ww := &utils.StatusWriter{ResponseWriter: w, StatusCode: http.StatusOK}
+			tracer := cfg.tracerProvider().Tracer(tracerName)
+			start := time.Now()
+
+			var forced []attribute.KeyValue
+			if cfg.sampling.forced(r) {
+				forced = append(forced, SamplingPriorityKey.Int(1))
+			}
+
+			// The span is started even when the sampler drops it, so the handler
+			// still carries the trace of the caller and its sampling decision.
+			ctx, span := tracer.Start(parentCtx, cfg.spanName(r), cfg.startOptions(r, start, forced...)...)
+			defer cfg.finish(span, r, ww)
+
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			if !span.IsRecording() && cfg.sampling.SampleErrors && ww.StatusCode >= http.StatusInternalServerError {
+				_, errorSpan := tracer.Start(parentCtx, cfg.spanName(r), cfg.startOptions(r, start, SamplingPriorityKey.Int(1))...)
+				cfg.finish(errorSpan, r, ww)
+			}
+		})
+	}
+}
+
+func (c config) startOptions(r *http.Request, start time.Time, attrs ...attribute.KeyValue) []trace.SpanStartOption {
+	return []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(append(requestAttributes(r), attrs...)...),
+		trace.WithTimestamp(start),
+	}
+}
 
-			return
+// finish records the outcome of the request on the span and ends it.
+func (c config) finish(span trace.Span, r *http.Request, ww *utils.StatusWriter) {
+	defer span.End()
+
+	if !span.IsRecording() {
+		return
+	}
+
+	span.SetStatus(ww.GetStatus())
+	span.SetName(c.spanName(r))
+	span.SetAttributes(responseAttributes(r, ww)...)
+
+	for _, enrich := range c.enrichers {
+		span.SetAttributes(enrich(r)...)
+	}
+}
+
+func (c config) traced(r *http.Request) bool {
+	if utils.CheckInValidPath(r) {
+		return false
+	}
+
+	for _, filter := range c.filters {
+		if !filter(r) {
+			return false
 		}
+	}
+
+	return true
+}
+
+func (c config) tracerProvider() trace.TracerProvider {
+	if c.provider != nil {
+		return c.provider
+	}
 
-		defaultCtx := baggage.ContextWithoutBaggage(r.Context())
-		ww := &utils.StatusWriter{ResponseWriter: w, StatusCode: http.StatusOK}
-
-		// Start a new span for the request
-		tracer := otel.GetTracerProvider().Tracer(tracerName)
-		ctx, span := tracer.Start(defaultCtx, r.URL.Path)
-		defer span.End()
-
-		next.ServeHTTP(ww, r.WithContext(ctx))
-
-		routePattern := chi.RouteContext(defaultCtx).RoutePattern()
-		span.SetStatus(ww.GetStatus())
-		span.SetName(routePattern)
-		span.SetAttributes(
-			attribute.Key("extra_path").String(r.URL.Path),
-			semconv.HTTPStatusCode(ww.StatusCode),
-			semconv.HTTPMethod(r.Method),
-			semconv.HTTPURL(getFullURL(r)),
-		)
-	})
+	return otel.GetTracerProvider()
 }
 
-func getFullURL(r *http.Request) string {
-	scheme := "http"
-	if r.Header.Get("X-Forwarded-Proto") == "https" {
-		scheme = "https"
+func (c config) textMapPropagator() propagation.TextMapPropagator {
+	if c.propagator != nil {
+		return c.propagator
 	}
 
-	return scheme + "://" + r.Host + r.RequestURI
+	return otel.GetTextMapPropagator()
 }
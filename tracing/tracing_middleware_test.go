@@ -0,0 +1,328 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	parentTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	parentSpanID  = "00f067aa0ba902b7"
+)
+
+// setupTracing installs an in-memory recorder and propagator as the globals
+// for the duration of the test.
+func setupTracing(t *testing.T, propagators ...string) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+
+	previousProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	propagator, err := NewPropagator(propagators...)
+	require.NoError(t, err)
+
+	previousPropagator := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagator)
+
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	return recorder
+}
+
+func tracedRouter(handler http.HandlerFunc) http.Handler {
+	router := chi.NewRouter()
+	router.Use(TracingMiddleware)
+	router.Get("/items/{id}", handler)
+
+	return router
+}
+
+func TestTracing_Propagation(t *testing.T) {
+	t.Run("should continue the W3C trace of the caller as a server span", func(t *testing.T) {
+		recorder := setupTracing(t, DefaultPropagators()...)
+
+		var handlerSpan trace.SpanContext
+
+		router := tracedRouter(func(w http.ResponseWriter, r *http.Request) {
+			handlerSpan = trace.SpanContextFromContext(r.Context())
+		})
+
+		r := httptest.NewRequest(http.MethodGet, "/items/42", nil)
+		r.Header.Set("traceparent", "00-"+parentTraceID+"-"+parentSpanID+"-01")
+		r.Header.Set("tracestate", "vendor=value")
+		router.ServeHTTP(httptest.NewRecorder(), r)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+
+		span := spans[0]
+		assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+		assert.Equal(t, parentTraceID, span.SpanContext().TraceID().String())
+		assert.Equal(t, parentSpanID, span.Parent().SpanID().String())
+		assert.True(t, span.Parent().IsRemote())
+		assert.Equal(t, "vendor=value", span.SpanContext().TraceState().String())
+		assert.Equal(t, span.SpanContext().SpanID(), handlerSpan.SpanID())
+		assert.Equal(t, "GET /items/{id}", span.Name())
+	})
+
+	t.Run("should start a root span without an incoming trace", func(t *testing.T) {
+		recorder := setupTracing(t, DefaultPropagators()...)
+
+		router := tracedRouter(func(w http.ResponseWriter, r *http.Request) {})
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/42", nil))
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		assert.False(t, spans[0].Parent().IsValid())
+	})
+
+	t.Run("should expose the incoming baggage to the handler", func(t *testing.T) {
+		setupTracing(t, DefaultPropagators()...)
+
+		var tenant string
+
+		router := tracedRouter(func(w http.ResponseWriter, r *http.Request) {
+			tenant = baggage.FromContext(r.Context()).Member("tenant").Value()
+		})
+
+		r := httptest.NewRequest(http.MethodGet, "/items/42", nil)
+		r.Header.Set("baggage", "tenant=acme")
+		router.ServeHTTP(httptest.NewRecorder(), r)
+
+		assert.Equal(t, "acme", tenant)
+	})
+
+	t.Run("should continue B3 and Jaeger traces", func(t *testing.T) {
+		headers := map[string]map[string]string{
+			PropagatorB3: {"b3": parentTraceID + "-" + parentSpanID + "-1"},
+			PropagatorB3Multi: {
+				"X-B3-TraceId": parentTraceID,
+				"X-B3-SpanId":  parentSpanID,
+				"X-B3-Sampled": "1",
+			},
+			PropagatorJaeger: {"uber-trace-id": parentTraceID + ":" + parentSpanID + ":0:1"},
+		}
+
+		for name, header := range headers {
+			recorder := setupTracing(t, PropagatorTraceContext, name)
+
+			r := httptest.NewRequest(http.MethodGet, "/items/42", nil)
+			for key, value := range header {
+				r.Header.Set(key, value)
+			}
+
+			tracedRouter(func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(httptest.NewRecorder(), r)
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 1, name)
+			assert.Equal(t, parentTraceID, spans[0].SpanContext().TraceID().String(), name)
+			assert.Equal(t, parentSpanID, spans[0].Parent().SpanID().String(), name)
+		}
+	})
+}
+
+func TestTracing_NewPropagator(t *testing.T) {
+	t.Run("should combine the named propagators", func(t *testing.T) {
+		propagator, err := NewPropagator("tracecontext", " Baggage ", "b3")
+		require.NoError(t, err)
+
+		assert.ElementsMatch(t, []string{"traceparent", "tracestate", "baggage", "b3"}, propagator.Fields())
+	})
+
+	t.Run("should reject unknown propagators", func(t *testing.T) {
+		_, err := NewPropagator("tracecontext", "xray")
+		assert.ErrorIs(t, err, ErrUnknownPropagator)
+	})
+
+	t.Run("should propagate nothing without propagators", func(t *testing.T) {
+		propagator, err := NewPropagator()
+		require.NoError(t, err)
+
+		assert.Empty(t, propagator.Fields())
+		assert.IsType(t, propagation.NewCompositeTextMapPropagator(), propagator)
+	})
+}
+
+func TestTracing_New(t *testing.T) {
+	newRouter := func(opts ...Option) http.Handler {
+		router := chi.NewRouter()
+		router.Use(New(opts...))
+		router.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+		return router
+	}
+
+	t.Run("should record spans on the given provider rather than the global one", func(t *testing.T) {
+		global := setupTracing(t)
+		recorder := tracetest.NewSpanRecorder()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+		router := newRouter(WithTracerProvider(provider))
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/42", nil))
+
+		require.Len(t, recorder.Ended(), 1)
+		assert.Equal(t, "GET /items/{id}", recorder.Ended()[0].Name())
+		assert.Equal(t, tracerName, recorder.Ended()[0].InstrumentationScope().Name)
+		assert.Empty(t, global.Ended())
+	})
+
+	t.Run("should extract the trace context with the given propagator", func(t *testing.T) {
+		setupTracing(t, PropagatorTraceContext)
+		recorder := tracetest.NewSpanRecorder()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+		propagator, err := NewPropagator(PropagatorB3)
+		require.NoError(t, err)
+
+		router := newRouter(WithTracerProvider(provider), WithPropagator(propagator))
+
+		r := httptest.NewRequest(http.MethodGet, "/items/42", nil)
+		r.Header.Set("b3", parentTraceID+"-"+parentSpanID+"-1")
+		router.ServeHTTP(httptest.NewRecorder(), r)
+
+		require.Len(t, recorder.Ended(), 1)
+		assert.Equal(t, parentTraceID, recorder.Ended()[0].SpanContext().TraceID().String())
+	})
+
+	t.Run("should name spans and add attributes after the request is routed", func(t *testing.T) {
+		recorder := tracetest.NewSpanRecorder()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+		router := newRouter(
+			WithTracerProvider(provider),
+			WithSpanNameFormatter(func(r *http.Request) string {
+				return "items " + Route(r)
+			}),
+			WithAttributes(func(r *http.Request) []attribute.KeyValue {
+				return []attribute.KeyValue{attribute.String("item.id", chi.URLParam(r, "id"))}
+			}),
+		)
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/42", nil))
+
+		require.Len(t, recorder.Ended(), 1)
+
+		span := recorder.Ended()[0]
+		assert.Equal(t, "items /items/{id}", span.Name())
+		assert.Contains(t, span.Attributes(), attribute.String("item.id", "42"))
+	})
+
+	t.Run("should only trace the requests accepted by every filter", func(t *testing.T) {
+		recorder := tracetest.NewSpanRecorder()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+		router := newRouter(
+			WithTracerProvider(provider),
+			WithFilter(func(r *http.Request) bool {
+				return r.Header.Get("X-Internal") == ""
+			}),
+		)
+
+		r := httptest.NewRequest(http.MethodGet, "/items/42", nil)
+		r.Header.Set("X-Internal", "1")
+		router.ServeHTTP(httptest.NewRecorder(), r)
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/status", nil))
+		assert.Empty(t, recorder.Ended())
+
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/items/42", nil))
+		assert.Len(t, recorder.Ended(), 1)
+	})
+}
+
+func TestTracing_SemanticConventions(t *testing.T) {
+	newRecorder := func() (*tracetest.SpanRecorder, Option) {
+		recorder := tracetest.NewSpanRecorder()
+
+		return recorder, WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	}
+
+	t.Run("should emit the stable HTTP server attributes", func(t *testing.T) {
+		recorder, provider := newRecorder()
+
+		router := chi.NewRouter()
+		router.Use(New(provider))
+		router.Post("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte("created"))
+		})
+
+		r := httptest.NewRequest(http.MethodPost, "https://api.example.com:8443/items/42", strings.NewReader(`{"a":1}`))
+		r.Header.Set("User-Agent", "tests/1.0")
+		router.ServeHTTP(httptest.NewRecorder(), r)
+
+		require.Len(t, recorder.Ended(), 1)
+
+		span := recorder.Ended()[0]
+		assert.Equal(t, "POST /items/{id}", span.Name())
+		assert.Subset(t, span.Attributes(), []attribute.KeyValue{
+			attribute.String("http.request.method", http.MethodPost),
+			attribute.String("http.route", "/items/{id}"),
+			attribute.String("url.path", "/items/42"),
+			attribute.String("url.scheme", "https"),
+			attribute.String("server.address", "api.example.com"),
+			attribute.Int("server.port", 8443),
+			attribute.String("client.address", "192.0.2.1"),
+			attribute.String("user_agent.original", "tests/1.0"),
+			attribute.String("network.protocol.version", "1.1"),
+			attribute.Int("http.request.body.size", 7),
+			attribute.Int("http.response.status_code", http.StatusCreated),
+			attribute.Int("http.response.body.size", 7),
+		})
+	})
+
+	t.Run("should name the span after the route when it starts", func(t *testing.T) {
+		recorder, provider := newRecorder()
+
+		var startName string
+
+		api := chi.NewRouter()
+		api.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+			startName = trace.SpanFromContext(r.Context()).(sdktrace.ReadOnlySpan).Name()
+		})
+
+		router := chi.NewRouter()
+		router.Use(New(provider))
+		router.Mount("/api", api)
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/items/42", nil))
+
+		assert.Equal(t, "GET /api/items/{id}", startName)
+		require.Len(t, recorder.Ended(), 1)
+		assert.Equal(t, "GET /api/items/{id}", recorder.Ended()[0].Name())
+	})
+
+	t.Run("should name the span after the method when no route matches", func(t *testing.T) {
+		recorder, provider := newRecorder()
+
+		router := chi.NewRouter()
+		router.Use(New(provider))
+		router.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {})
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PURGE", "/unknown", nil))
+
+		require.Len(t, recorder.Ended(), 1)
+
+		span := recorder.Ended()[0]
+		assert.Equal(t, "HTTP", span.Name())
+		assert.Subset(t, span.Attributes(), []attribute.KeyValue{
+			attribute.String("http.request.method", "_OTHER"),
+			attribute.String("http.request.method_original", "PURGE"),
+			attribute.Int("http.response.status_code", http.StatusMethodNotAllowed),
+		})
+	})
+}
@@ -0,0 +1,290 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type (
+	// ProxyResolver resolves the client address, scheme and host of requests
+	// from the forwarding headers, trusting them only when they were set by
+	// one of its trusted proxies.
+	ProxyResolver struct {
+		proxies []netip.Prefix
+	}
+
+	forwarded struct {
+		clientIP string
+		scheme   string
+		host     string
+	}
+
+	forwardedKey struct{}
+)
+
+// ParseCIDRs parses CIDRs and single addresses, which match only themselves.
+func ParseCIDRs(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %q", cidr)
+			}
+
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", cidr)
+		}
+
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
+
+// NewProxyResolver trusts the proxies within trustedCIDRs. Without any, the
+// forwarding headers are always ignored.
+func NewProxyResolver(trustedCIDRs []string) (*ProxyResolver, error) {
+	proxies, err := ParseCIDRs(trustedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProxyResolver{proxies: proxies}, nil
+}
+
+// Middleware resolves the request for ClientIP, Scheme and Host, and sets
+// r.RemoteAddr to the client address when it was forwarded.
+func (p *ProxyResolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fwd := p.resolve(r)
+
+		if fwd.clientIP != remoteIP(r) {
+			r.RemoteAddr = fwd.clientIP
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), forwardedKey{}, fwd)))
+	})
+}
+
+func (p *ProxyResolver) resolve(r *http.Request) forwarded {
+	fwd := forwarded{clientIP: remoteIP(r), scheme: "http", host: r.Host}
+	if r.TLS != nil {
+		fwd.scheme = "https"
+	}
+
+	if !p.trustedPeer(fwd.clientIP) {
+		return fwd
+	}
+
+	switch {
+	case r.Header.Get("Forwarded") != "":
+		p.resolveForwarded(r, &fwd)
+	case r.Header.Get("X-Forwarded-For") != "":
+		p.resolveXForwarded(r, &fwd)
+	case r.Header.Get("X-Real-IP") != "":
+		if addr, ok := parseNode(r.Header.Get("X-Real-IP")); ok {
+			fwd.clientIP = addr.String()
+		}
+	}
+
+	return fwd
+}
+
+// resolveForwarded walks the RFC 7239 elements from the nearest proxy and
+// stops at the first untrusted address, which is the client. Its element also
+// carries the scheme and host the client used.
+func (p *ProxyResolver) resolveForwarded(r *http.Request, fwd *forwarded) {
+	elements := forwardedElements(r.Header.Values("Forwarded"))
+
+	client := -1
+
+	for i := len(elements) - 1; i >= 0; i-- {
+		addr, ok := parseNode(elements[i]["for"])
+		if !ok {
+			break
+		}
+
+		fwd.clientIP = addr.String()
+		client = i
+
+		if !p.trusted(addr) {
+			break
+		}
+	}
+
+	if client < 0 {
+		return
+	}
+
+	if proto := elements[client]["proto"]; proto != "" {
+		fwd.scheme = strings.ToLower(proto)
+	}
+
+	if host := elements[client]["host"]; host != "" {
+		fwd.host = host
+	}
+}
+
+// resolveXForwarded walks X-Forwarded-For from the nearest proxy like
+// resolveForwarded. X-Forwarded-Proto and X-Forwarded-Host are taken from the
+// nearest proxy.
+func (p *ProxyResolver) resolveXForwarded(r *http.Request, fwd *forwarded) {
+	hops := headerList(r.Header.Values("X-Forwarded-For"))
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseNode(hops[i])
+		if !ok {
+			break
+		}
+
+		fwd.clientIP = addr.String()
+
+		if !p.trusted(addr) {
+			break
+		}
+	}
+
+	if protos := headerList(r.Header.Values("X-Forwarded-Proto")); len(protos) > 0 {
+		fwd.scheme = strings.ToLower(protos[len(protos)-1])
+	}
+
+	if hosts := headerList(r.Header.Values("X-Forwarded-Host")); len(hosts) > 0 {
+		fwd.host = hosts[len(hosts)-1]
+	}
+}
+
+// trustedPeer reports whether the forwarding headers of the direct peer are
+// trusted. Peers without an IP address, such as on a Unix socket, are trusted
+// as soon as any proxy is.
+func (p *ProxyResolver) trustedPeer(peer string) bool {
+	if len(p.proxies) == 0 {
+		return false
+	}
+
+	addr, err := netip.ParseAddr(peer)
+	if err != nil {
+		return true
+	}
+
+	return p.trusted(addr.Unmap())
+}
+
+func (p *ProxyResolver) trusted(addr netip.Addr) bool {
+	for _, prefix := range p.proxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientIP returns the address of the client, resolved from the forwarding
+// headers of trusted proxies when the ProxyResolver middleware ran.
+func ClientIP(r *http.Request) string {
+	if fwd, ok := r.Context().Value(forwardedKey{}).(forwarded); ok {
+		return fwd.clientIP
+	}
+
+	return remoteIP(r)
+}
+
+// Scheme returns the scheme the client used, "http" or "https".
+func Scheme(r *http.Request) string {
+	if fwd, ok := r.Context().Value(forwardedKey{}).(forwarded); ok {
+		return fwd.scheme
+	}
+
+	if r.TLS != nil {
+		return "https"
+	}
+
+	return "http"
+}
+
+// Host returns the host the client addressed.
+func Host(r *http.Request) string {
+	if fwd, ok := r.Context().Value(forwardedKey{}).(forwarded); ok {
+		return fwd.host
+	}
+
+	return r.Host
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// parseNode parses an address as found in forwarding headers, with an
+// optional port and brackets around IPv6 addresses.
+func parseNode(node string) (netip.Addr, bool) {
+	node = strings.Trim(strings.TrimSpace(node), `"`)
+
+	if addrPort, err := netip.ParseAddrPort(node); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(node, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), true
+}
+
+// forwardedElements splits Forwarded headers into their elements, each a
+// map of lower-cased parameter names to unquoted values.
+func forwardedElements(values []string) []map[string]string {
+	var elements []map[string]string
+
+	for _, element := range headerList(values) {
+		params := map[string]string{}
+
+		for _, pair := range strings.Split(element, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok {
+				continue
+			}
+
+			params[strings.ToLower(key)] = strings.Trim(value, `"`)
+		}
+
+		elements = append(elements, params)
+	}
+
+	return elements
+}
+
+func headerList(values []string) []string {
+	var list []string
+
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+
+	return list
+}
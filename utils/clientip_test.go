@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// resolved runs r through a resolver trusting trusted and returns the
+// client address, scheme and host seen by the handler.
+func resolved(t *testing.T, trusted []string, r *http.Request) (string, string, string) {
+	t.Helper()
+
+	resolver, err := NewProxyResolver(trusted)
+	require.NoError(t, err)
+
+	var clientIP, scheme, host string
+
+	resolver.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientIP, scheme, host = ClientIP(r), Scheme(r), Host(r)
+	})).ServeHTTP(httptest.NewRecorder(), r)
+
+	return clientIP, scheme, host
+}
+
+func forwardedRequest(remoteAddr string, headers map[string]string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "http://api.internal/test", nil)
+	r.RemoteAddr = remoteAddr
+
+	for key, value := range headers {
+		r.Header.Set(key, value)
+	}
+
+	return r
+}
+
+func TestUtil_ProxyResolver(t *testing.T) {
+	trusted := []string{"10.0.0.0/8", "2001:db8::1"}
+
+	t.Run("should ignore forwarding headers from untrusted peers", func(t *testing.T) {
+		r := forwardedRequest("203.0.113.9:4000", map[string]string{
+			"X-Forwarded-For":   "198.51.100.1",
+			"X-Forwarded-Proto": "https",
+			"X-Real-IP":         "198.51.100.2",
+		})
+
+		clientIP, scheme, host := resolved(t, trusted, r)
+
+		assert.Equal(t, "203.0.113.9", clientIP)
+		assert.Equal(t, "http", scheme)
+		assert.Equal(t, "api.internal", host)
+	})
+
+	t.Run("should ignore forwarding headers without trusted proxies", func(t *testing.T) {
+		r := forwardedRequest("10.0.0.1:4000", map[string]string{"X-Forwarded-For": "198.51.100.1"})
+
+		clientIP, _, _ := resolved(t, nil, r)
+
+		assert.Equal(t, "10.0.0.1", clientIP)
+	})
+
+	t.Run("should take the first untrusted hop of X-Forwarded-For", func(t *testing.T) {
+		r := forwardedRequest("10.0.0.1:4000", map[string]string{
+			"X-Forwarded-For":   "192.0.2.66, 198.51.100.1, 10.0.0.7",
+			"X-Forwarded-Proto": "https",
+			"X-Forwarded-Host":  "api.example.com",
+		})
+
+		clientIP, scheme, host := resolved(t, trusted, r)
+
+		assert.Equal(t, "198.51.100.1", clientIP)
+		assert.Equal(t, "https", scheme)
+		assert.Equal(t, "api.example.com", host)
+	})
+
+	t.Run("should take the leftmost hop when every hop is trusted", func(t *testing.T) {
+		r := forwardedRequest("10.0.0.1:4000", map[string]string{"X-Forwarded-For": "10.0.0.9, 10.0.0.7"})
+
+		clientIP, _, _ := resolved(t, trusted, r)
+
+		assert.Equal(t, "10.0.0.9", clientIP)
+	})
+
+	t.Run("should parse RFC 7239 Forwarded", func(t *testing.T) {
+		r := forwardedRequest("[2001:db8::1]:4000", map[string]string{
+			"Forwarded":       `for="[2001:db8:cafe::17]:4711";proto=https;host=api.example.com, for=10.0.0.7;proto=http`,
+			"X-Forwarded-For": "192.0.2.66",
+		})
+
+		clientIP, scheme, host := resolved(t, trusted, r)
+
+		assert.Equal(t, "2001:db8:cafe::17", clientIP)
+		assert.Equal(t, "https", scheme)
+		assert.Equal(t, "api.example.com", host)
+	})
+
+	t.Run("should stop at obfuscated Forwarded nodes", func(t *testing.T) {
+		r := forwardedRequest("10.0.0.1:4000", map[string]string{"Forwarded": "for=192.0.2.66, for=unknown, for=10.0.0.7"})
+
+		clientIP, _, _ := resolved(t, trusted, r)
+
+		assert.Equal(t, "10.0.0.7", clientIP)
+	})
+
+	t.Run("should fall back to X-Real-IP", func(t *testing.T) {
+		r := forwardedRequest("10.0.0.1:4000", map[string]string{"X-Real-IP": "198.51.100.1"})
+
+		clientIP, _, _ := resolved(t, trusted, r)
+
+		assert.Equal(t, "198.51.100.1", clientIP)
+	})
+
+	t.Run("should set RemoteAddr to the forwarded client", func(t *testing.T) {
+		r := forwardedRequest("10.0.0.1:4000", map[string]string{"X-Forwarded-For": "198.51.100.1"})
+
+		resolver, err := NewProxyResolver(trusted)
+		require.NoError(t, err)
+
+		var remoteAddr string
+
+		resolver.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			remoteAddr = r.RemoteAddr
+		})).ServeHTTP(httptest.NewRecorder(), r)
+
+		assert.Equal(t, "198.51.100.1", remoteAddr)
+	})
+
+	t.Run("should reject invalid CIDRs", func(t *testing.T) {
+		_, err := NewProxyResolver([]string{"10.0.0.0/40"})
+		assert.Error(t, err)
+	})
+}
+
+func TestUtil_ClientIP(t *testing.T) {
+	t.Run("should use the connection without the resolver", func(t *testing.T) {
+		r := forwardedRequest("203.0.113.9:4000", map[string]string{"X-Forwarded-For": "198.51.100.1"})
+		r.TLS = &tls.ConnectionState{}
+
+		assert.Equal(t, "203.0.113.9", ClientIP(r))
+		assert.Equal(t, "https", Scheme(r))
+		assert.Equal(t, "api.internal", Host(r))
+	})
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"context"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type (
+	// HostMatch describes the host route a request was dispatched to.
+	HostMatch struct {
+		// Pattern is the matched host pattern, such as "*.tenant.example.com".
+		Pattern string
+		// Wildcards holds the labels matched by each "*" of Pattern, in order.
+		Wildcards []string
+		// Routes is the router of the host, which serves the request once it
+		// is dispatched.
+		Routes chi.Routes
+	}
+
+	hostMatchKey struct{}
+)
+
+func ContextWithHostMatch(ctx context.Context, match HostMatch) context.Context {
+	return context.WithValue(ctx, hostMatchKey{}, match)
+}
+
+// ContextWithoutHostMatch hides the host route of an outer router, for a router
+// whose own host routes did not match.
+func ContextWithoutHostMatch(ctx context.Context) context.Context {
+	if _, ok := HostMatchFromContext(ctx); !ok {
+		return ctx
+	}
+
+	return context.WithValue(ctx, hostMatchKey{}, nil)
+}
+
+// HostMatchFromContext returns the host route of the request, if it matched
+// one.
+func HostMatchFromContext(ctx context.Context) (HostMatch, bool) {
+	match, ok := ctx.Value(hostMatchKey{}).(HostMatch)
+
+	return match, ok
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"net/http"
 	"slices"
+	"strconv"
 
 	otelcodes "go.opentelemetry.io/otel/codes"
 )
@@ -14,22 +15,41 @@ const (
 	DefaultrStatusMsg = "Unhandled Status"
 )
 
-var invalidPaths = []string{"/metrics", "/status", "/ready", "/", "/*"}
+var invalidPaths = []string{"/metrics", "/status", "/ready", "/live", "/startup", "/", "/*"}
 
+// StatusWriter records the status code and the number of body bytes written
+// to the response.
 type StatusWriter struct {
 	http.ResponseWriter
 	StatusCode int
+	Bytes      int
 }
 
 func CheckInValidPath(r *http.Request) bool {
 	return slices.Contains(invalidPaths, r.URL.Path)
 }
 
+// ProtocolVersion returns the HTTP version of the request, such as "1.1" or "2".
+func ProtocolVersion(r *http.Request) string {
+	if r.ProtoMajor >= 2 {
+		return strconv.Itoa(r.ProtoMajor)
+	}
+
+	return strconv.Itoa(r.ProtoMajor) + "." + strconv.Itoa(r.ProtoMinor)
+}
+
 func (sw *StatusWriter) WriteHeader(code int) {
 	sw.StatusCode = code
 	sw.ResponseWriter.WriteHeader(code)
 }
 
+func (sw *StatusWriter) Write(b []byte) (int, error) {
+	n, err := sw.ResponseWriter.Write(b)
+	sw.Bytes += n
+
+	return n, err
+}
+
 func (sw *StatusWriter) GetStatus() (otelcodes.Code, string) {
 	if sw.StatusCode >= 200 && sw.StatusCode < 300 {
 		return otelcodes.Ok, OKStatusMsg
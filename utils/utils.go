@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
@@ -16,6 +17,9 @@ const (
 	InvalidBody        = "invalid_body"
 	InvalidParam       = "invalid_param"
 	InvalidCredentials = "invalid_credentials"
+	Forbidden          = "forbidden"
+	BodyTooLarge       = "body_too_large"
+	RequestTimeout     = "request_timeout"
 	InternalCode       = "internal_server_error"
 
 	ContentType                  = "Content-Type"
@@ -28,8 +32,10 @@ const (
 )
 
 var (
-	ErrEmptyBody   = errors.New("body is empty")
-	ErrInvalidBody = errors.New("body is invalid")
+	ErrEmptyBody    = errors.New("body is empty")
+	ErrInvalidBody  = errors.New("body is invalid")
+	ErrBodyTooLarge = errors.New("body is too large")
+	ErrBodyTimeout  = errors.New("body was not received in time")
 )
 
 type Error struct {
@@ -41,7 +47,16 @@ type Error struct {
 func ReadBody(r *http.Request, v interface{}) error {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		return ErrInvalidBody
+		var maxBytesErr *http.MaxBytesError
+
+		switch {
+		case errors.As(err, &maxBytesErr):
+			return ErrBodyTooLarge
+		case errors.Is(err, os.ErrDeadlineExceeded):
+			return ErrBodyTimeout
+		default:
+			return ErrInvalidBody
+		}
 	}
 
 	if len(body) == 0 {
@@ -55,6 +70,18 @@ func ReadBody(r *http.Request, v interface{}) error {
 	return nil
 }
 
+// BodyFault answers a ReadBody error with 413, 408 or 400.
+func BodyFault(w http.ResponseWriter, err error) error {
+	switch {
+	case errors.Is(err, ErrBodyTooLarge):
+		return Fault(w, http.StatusRequestEntityTooLarge, BodyTooLarge, err.Error())
+	case errors.Is(err, ErrBodyTimeout):
+		return Fault(w, http.StatusRequestTimeout, RequestTimeout, err.Error())
+	default:
+		return Fault(w, http.StatusBadRequest, InvalidBody, err.Error())
+	}
+}
+
 func WriteBody(w http.ResponseWriter, statusCode int, body interface{}) error {
 	result, err := json.Marshal(body)
 	if err != nil {
@@ -365,3 +365,18 @@ func TestUtil_ParseFloatQuery(t *testing.T) {
 		assert.Equal(t, "accountID is invalid type", reqErr.Message)
 	})
 }
+
+func TestUtil_StatusWriter(t *testing.T) {
+	t.Run("should record the status code and the body size", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		sw := &StatusWriter{ResponseWriter: w, StatusCode: http.StatusOK}
+
+		sw.WriteHeader(http.StatusAccepted)
+		_, _ = sw.Write([]byte("hello "))
+		_, _ = sw.Write([]byte("world"))
+
+		assert.Equal(t, http.StatusAccepted, sw.StatusCode)
+		assert.Equal(t, 11, sw.Bytes)
+		assert.Equal(t, "hello world", w.Body.String())
+	})
+}